@@ -13,6 +13,10 @@ import (
 
 var DB *sql.DB
 
+// ReadDB is a pool of read-only connections for long running reads such as exports. With the
+// database in WAL mode a query on it reads one snapshot and does not hold up writes through DB.
+var ReadDB *sql.DB
+
 func InitDB(dbPath string) error {
 	var err error
 	DB, err = sql.Open("sqlite", dbPath)
@@ -35,6 +39,18 @@ func InitDB(dbPath string) error {
 		return err
 	}
 
+	// Let readers keep a snapshot while the single writer connection goes on
+	_, err = DB.Exec("PRAGMA journal_mode = WAL")
+	if err != nil {
+		return err
+	}
+
+	ReadDB, err = sql.Open("sqlite", dbPath+"?_pragma=query_only(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return err
+	}
+	ReadDB.SetMaxOpenConns(4)
+
 	log.Println("Database connection established")
 
 	// Use a proper migrations directory path instead of the db file path
@@ -69,6 +85,9 @@ func RunMigration(db *sql.DB, migrationsPath string) error {
 }
 
 func CloseDB() {
+	if ReadDB != nil {
+		ReadDB.Close()
+	}
 	if DB != nil {
 		DB.Close()
 	}
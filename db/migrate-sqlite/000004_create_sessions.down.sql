@@ -0,0 +1,2 @@
+drop index if exists idx_clothing_sessions_users;
+drop table if exists clothing_sessions;
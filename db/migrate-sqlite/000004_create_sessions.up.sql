@@ -0,0 +1,25 @@
+-- clothing_sessions contains all the login sessions of the users
+-- id contains the id for session
+-- id_clothing_users contains the id for the users
+-- session_token_hash contains the sha-256 hash of the opaque session token, the raw token is only kept in the cookie
+-- session_user_agent contains the user agent of the client limit to 256 characters
+-- session_client_ip contains the ip address of the client limit to 64 characters
+-- session_status contains the status of the session: 1 = active, 2 = revoked, 3 = expired
+-- session_expires_at contains the date and time when the session expires, pushed forward on every request
+-- session_last_seen_at contains the date and time when the session was last used
+-- created_at contains the date and time when the session is created
+-- updated_at contains the date and time when the session is updated
+create table if not exists clothing_sessions (
+    id integer primary key,
+    id_clothing_users integer not null REFERENCES clothing_users(id),
+    session_token_hash text not null unique,
+    session_user_agent text not null default '',
+    session_client_ip text not null default '',
+    session_status integer not null default 1,
+    session_expires_at datetime not null,
+    session_last_seen_at datetime not null,
+    created_at datetime not null,
+    updated_at datetime not null
+);
+
+create index if not exists idx_clothing_sessions_users on clothing_sessions (id_clothing_users, session_status);
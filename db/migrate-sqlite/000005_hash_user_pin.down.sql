@@ -0,0 +1 @@
+alter table clothing_users drop column pin_hash;
@@ -0,0 +1,4 @@
+-- pin_hash contains the salted bcrypt hash of the 6 digit pin of the users
+-- the legacy plaintext pin column is converted to pin_hash on startup and then zeroed,
+-- a user whose pin_hash is still empty is upgraded on the next successful login
+alter table clothing_users add column pin_hash text not null default '';
@@ -0,0 +1 @@
+alter table clothing_users drop column user_role;
@@ -0,0 +1,4 @@
+-- user_role contains the role of the users: 1 = owner, 2 = manager, 3 = cashier, 4 = viewer
+-- users created before roles existed could do everything, so they start out as owner
+alter table clothing_users add column user_role integer not null default 4;
+update clothing_users set user_role = 1;
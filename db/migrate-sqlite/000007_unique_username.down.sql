@@ -0,0 +1 @@
+drop index if exists idx_clothing_users_username;
@@ -0,0 +1,2 @@
+-- usernames are unique regardless of case, inactive users keep their username
+create unique index if not exists idx_clothing_users_username on clothing_users (username collate nocase);
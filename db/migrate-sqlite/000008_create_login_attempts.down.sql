@@ -0,0 +1 @@
+drop table if exists clothing_login_attempts;
@@ -0,0 +1,22 @@
+-- clothing_login_attempts contains the failed login counters used to throttle and lock out logins
+-- id contains the id for login attempt
+-- attempt_key_type contains the type of the key: 1 = username, 2 = client ip
+-- attempt_key contains the lower cased username or the client ip limit to 64 characters
+-- attempt_failed_count contains the number of consecutive failed logins
+-- attempt_last_failed_at contains the date and time of the last failed login
+-- attempt_locked_until contains the date and time until the key is locked out, null when not locked
+-- attempt_suspended_user contains 1 when the lockout suspended the user (user_status = 3), 0 otherwise
+-- created_at contains the date and time when the login attempt is created
+-- updated_at contains the date and time when the login attempt is updated
+create table if not exists clothing_login_attempts (
+    id integer primary key,
+    attempt_key_type integer not null,
+    attempt_key text not null,
+    attempt_failed_count integer not null default 0,
+    attempt_last_failed_at datetime not null,
+    attempt_locked_until datetime,
+    attempt_suspended_user integer not null default 0,
+    created_at datetime not null,
+    updated_at datetime not null,
+    unique (attempt_key_type, attempt_key)
+);
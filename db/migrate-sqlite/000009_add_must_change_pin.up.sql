@@ -0,0 +1,2 @@
+-- must_change_pin contains 1 when the users has to pick a new pin before using the application, 0 otherwise
+alter table clothing_users add column must_change_pin integer not null default 0;
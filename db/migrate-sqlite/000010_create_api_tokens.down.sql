@@ -0,0 +1,2 @@
+drop index if exists idx_clothing_api_tokens_users;
+drop table if exists clothing_api_tokens;
@@ -0,0 +1,27 @@
+-- clothing_api_tokens contains the bearer tokens used by scanners and scripts instead of the session cookie
+-- id contains the id for api token
+-- id_clothing_users contains the id for the users the token acts as
+-- token_name contains the name of the token limit to 64 characters
+-- token_prefix contains the first characters of the token so it can be recognised without storing it
+-- token_hash contains the sha-256 hash of the token, the raw token is only shown once on creation
+-- token_scopes contains the comma separated permissions granted to the token
+-- token_status contains the status of the token: 1 = active, 2 = revoked
+-- token_expires_at contains the date and time when the token expires, null when it never expires
+-- token_last_used_at contains the date and time when the token was last used, null when never used
+-- created_at contains the date and time when the token is created
+-- updated_at contains the date and time when the token is updated
+create table if not exists clothing_api_tokens (
+    id integer primary key,
+    id_clothing_users integer not null REFERENCES clothing_users(id),
+    token_name text not null,
+    token_prefix text not null,
+    token_hash text not null unique,
+    token_scopes text not null default '',
+    token_status integer not null default 1,
+    token_expires_at datetime,
+    token_last_used_at datetime,
+    created_at datetime not null,
+    updated_at datetime not null
+);
+
+create index if not exists idx_clothing_api_tokens_users on clothing_api_tokens (id_clothing_users, token_status);
@@ -0,0 +1,4 @@
+drop index if exists idx_clothing_audit_log_created;
+drop index if exists idx_clothing_audit_log_users;
+drop index if exists idx_clothing_audit_log_entity;
+drop table if exists clothing_audit_log;
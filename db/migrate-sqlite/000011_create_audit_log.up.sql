@@ -0,0 +1,23 @@
+-- clothing_audit_log contains every mutating action done through the api
+-- id contains the id for audit log
+-- id_clothing_users contains the id for the users that did the action
+-- audit_action contains the action: 1 = create, 2 = update, 3 = delete, 4 = return
+-- audit_entity_type contains the table name of the changed entity limit to 64 characters
+-- audit_entity_id contains the id of the changed entity
+-- audit_diff contains the changed fields as json: {"field": {"before": ..., "after": ...}}
+-- audit_client_ip contains the ip address of the client limit to 64 characters
+-- created_at contains the date and time when the action happened
+create table if not exists clothing_audit_log (
+    id integer primary key,
+    id_clothing_users integer not null REFERENCES clothing_users(id),
+    audit_action integer not null,
+    audit_entity_type text not null,
+    audit_entity_id integer not null,
+    audit_diff text not null default '{}',
+    audit_client_ip text not null default '',
+    created_at datetime not null
+);
+
+create index if not exists idx_clothing_audit_log_entity on clothing_audit_log (audit_entity_type, audit_entity_id);
+create index if not exists idx_clothing_audit_log_users on clothing_audit_log (id_clothing_users, created_at);
+create index if not exists idx_clothing_audit_log_created on clothing_audit_log (created_at);
@@ -0,0 +1,6 @@
+drop table if exists clothing_login_challenges;
+drop index if exists idx_clothing_user_recovery_codes_users;
+drop table if exists clothing_user_recovery_codes;
+alter table clothing_users drop column totp_last_step;
+alter table clothing_users drop column totp_enabled;
+alter table clothing_users drop column totp_secret;
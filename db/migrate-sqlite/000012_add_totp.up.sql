@@ -0,0 +1,38 @@
+-- totp_secret contains the base32 rfc 6238 secret of the users, empty when not enrolled
+-- totp_enabled contains 1 when the users confirmed the enrollment and has to pass the totp challenge on login
+-- totp_last_step contains the last accepted time step, codes of that step or earlier are refused
+alter table clothing_users add column totp_secret text not null default '';
+alter table clothing_users add column totp_enabled integer not null default 0;
+alter table clothing_users add column totp_last_step integer not null default 0;
+
+-- clothing_user_recovery_codes contains the one-time recovery codes for users with totp enabled
+-- id contains the id for recovery code
+-- id_clothing_users contains the id for the users
+-- recovery_code_hash contains the sha-256 hash of the recovery code
+-- recovery_code_used_at contains the date and time when the code was used, null while unused
+-- created_at contains the date and time when the recovery code is created
+create table if not exists clothing_user_recovery_codes (
+    id integer primary key,
+    id_clothing_users integer not null REFERENCES clothing_users(id),
+    recovery_code_hash text not null,
+    recovery_code_used_at datetime,
+    created_at datetime not null
+);
+
+create index if not exists idx_clothing_user_recovery_codes_users on clothing_user_recovery_codes (id_clothing_users);
+
+-- clothing_login_challenges contains the pending second login step of users with totp enabled
+-- id contains the id for login challenge
+-- id_clothing_users contains the id for the users that passed the pin step
+-- challenge_token_hash contains the sha-256 hash of the challenge token handed to the client
+-- challenge_attempts contains the number of wrong codes entered for the challenge
+-- challenge_expires_at contains the date and time when the challenge expires
+-- created_at contains the date and time when the challenge is created
+create table if not exists clothing_login_challenges (
+    id integer primary key,
+    id_clothing_users integer not null REFERENCES clothing_users(id),
+    challenge_token_hash text not null unique,
+    challenge_attempts integer not null default 0,
+    challenge_expires_at datetime not null,
+    created_at datetime not null
+);
@@ -0,0 +1 @@
+alter table clothing_sessions drop column session_csrf_hash;
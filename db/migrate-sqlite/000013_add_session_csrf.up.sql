@@ -0,0 +1,3 @@
+-- session_csrf_hash contains the sha256 of the csrf token handed to the browser of this session,
+-- state changing requests have to echo the token in the X-CSRF-Token header
+alter table clothing_sessions add column session_csrf_hash text not null default '';
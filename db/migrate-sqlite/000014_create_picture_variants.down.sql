@@ -0,0 +1 @@
+drop table if exists clothing_picture_variants;
@@ -0,0 +1,20 @@
+-- clothing_picture_variants contains the resized copies of every subcategory picture
+-- id contains the id for the picture variant
+-- id_clothing_category_sub contains the id for the subcategory the picture belongs to
+-- picture_slot contains the picture slot of the subcategory: 1 to 5, matching clothes_picture_1..5
+-- picture_variant contains the size of the copy: preview or thumb, the full picture stays in clothes_picture_N
+-- picture_storage_key contains the key of the copy in the storage backend
+-- picture_width contains the width of the copy in pixels
+-- picture_height contains the height of the copy in pixels
+-- created_at contains the date and time when the copy is created
+create table if not exists clothing_picture_variants (
+    id integer primary key,
+    id_clothing_category_sub integer not null REFERENCES clothing_category_sub(id),
+    picture_slot integer not null,
+    picture_variant text not null,
+    picture_storage_key text not null,
+    picture_width integer not null,
+    picture_height integer not null,
+    created_at datetime not null,
+    unique (id_clothing_category_sub, picture_slot, picture_variant)
+);
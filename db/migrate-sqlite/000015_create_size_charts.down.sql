@@ -0,0 +1,4 @@
+drop index if exists idx_clothing_size_chart_entry;
+alter table clothing_size drop column id_clothing_size_chart_entry;
+drop table if exists clothing_size_chart_entry;
+drop table if exists clothing_size_chart;
@@ -0,0 +1,67 @@
+-- clothing_size_chart contains the named size templates that can be applied to a subcategory
+-- id contains the id for size chart
+-- size_chart_name contains the name of the size chart limit to 64 characters
+-- size_chart_notes contains the notes for the size chart limit to 256 characters
+-- size_chart_status contains the status of the size chart: 1 = active, 2 = inactive
+-- created_at contains the date and time when the size chart is created
+-- updated_at contains the date and time when the size chart is updated
+create table if not exists clothing_size_chart (
+    id integer primary key,
+    size_chart_name text not null unique collate nocase,
+    size_chart_notes text not null default '',
+    size_chart_status integer not null default 1,
+    created_at datetime not null,
+    updated_at datetime not null
+);
+
+-- clothing_size_chart_entry contains the sizes of a size chart, each becomes a clothing_size row when applied
+-- id contains the id for size chart entry
+-- id_clothing_size_chart contains the id for the size chart
+-- size_chart_entry_name contains the name of the size limit to 8 characters, copied to clothes_size_name
+-- size_chart_entry_notes contains the notes for the size limit to 256 characters, copied to clothes_size_notes
+-- size_chart_entry_order contains the position of the size within the chart, smallest first
+-- created_at contains the date and time when the size chart entry is created
+-- updated_at contains the date and time when the size chart entry is updated
+create table if not exists clothing_size_chart_entry (
+    id integer primary key,
+    id_clothing_size_chart integer not null REFERENCES clothing_size_chart(id),
+    size_chart_entry_name text not null,
+    size_chart_entry_notes text not null default '',
+    size_chart_entry_order integer not null default 0,
+    created_at datetime not null,
+    updated_at datetime not null,
+    unique (id_clothing_size_chart, size_chart_entry_name collate nocase)
+);
+
+-- id_clothing_size_chart_entry contains the id for the size chart entry the size was generated from, null for sizes entered by hand
+alter table clothing_size add column id_clothing_size_chart_entry integer;
+
+create index if not exists idx_clothing_size_chart_entry on clothing_size (id_clothing_size_chart_entry);
+
+INSERT INTO clothing_size_chart (id, size_chart_name, size_chart_notes, size_chart_status, created_at, updated_at)
+VALUES (1, 'Adult letter sizes', 'XS to XXL', 1, datetime('now'), datetime('now')),
+    (2, 'Kids by age', '2 to 14 years', 1, datetime('now'), datetime('now')),
+    (3, 'Numeric EU sizes', 'EU 34 to 48', 1, datetime('now'), datetime('now'));
+
+INSERT INTO clothing_size_chart_entry (id_clothing_size_chart, size_chart_entry_name, size_chart_entry_notes, size_chart_entry_order, created_at, updated_at)
+VALUES (1, 'XS', 'Extra Small', 1, datetime('now'), datetime('now')),
+    (1, 'S', 'Small', 2, datetime('now'), datetime('now')),
+    (1, 'M', 'Medium', 3, datetime('now'), datetime('now')),
+    (1, 'L', 'Large', 4, datetime('now'), datetime('now')),
+    (1, 'XL', 'Extra Large', 5, datetime('now'), datetime('now')),
+    (1, 'XXL', 'Double Extra Large', 6, datetime('now'), datetime('now')),
+    (2, '2Y', '2 years', 1, datetime('now'), datetime('now')),
+    (2, '4Y', '4 years', 2, datetime('now'), datetime('now')),
+    (2, '6Y', '6 years', 3, datetime('now'), datetime('now')),
+    (2, '8Y', '8 years', 4, datetime('now'), datetime('now')),
+    (2, '10Y', '10 years', 5, datetime('now'), datetime('now')),
+    (2, '12Y', '12 years', 6, datetime('now'), datetime('now')),
+    (2, '14Y', '14 years', 7, datetime('now'), datetime('now')),
+    (3, 'EU 34', '', 1, datetime('now'), datetime('now')),
+    (3, 'EU 36', '', 2, datetime('now'), datetime('now')),
+    (3, 'EU 38', '', 3, datetime('now'), datetime('now')),
+    (3, 'EU 40', '', 4, datetime('now'), datetime('now')),
+    (3, 'EU 42', '', 5, datetime('now'), datetime('now')),
+    (3, 'EU 44', '', 6, datetime('now'), datetime('now')),
+    (3, 'EU 46', '', 7, datetime('now'), datetime('now')),
+    (3, 'EU 48', '', 8, datetime('now'), datetime('now'));
@@ -0,0 +1,2 @@
+drop index if exists idx_clothing_size_measurement_type;
+drop table if exists clothing_size_measurement;
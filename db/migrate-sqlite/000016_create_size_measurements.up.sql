@@ -0,0 +1,20 @@
+-- clothing_size_measurement contains the structured measurements of a size, one row per kind of measurement
+-- id contains the id for size measurement
+-- id_clothing_size contains the id for the size
+-- measurement_type contains the kind of measurement: chest, waist, hip, length, sleeve or height (body height, for kids)
+-- measurement_min_mm contains the lower bound of the measurement in millimetres
+-- measurement_max_mm contains the upper bound of the measurement in millimetres, equal to the lower bound for a single value
+-- created_at contains the date and time when the size measurement is created
+-- updated_at contains the date and time when the size measurement is updated
+create table if not exists clothing_size_measurement (
+    id integer primary key,
+    id_clothing_size integer not null REFERENCES clothing_size(id),
+    measurement_type text not null,
+    measurement_min_mm integer not null,
+    measurement_max_mm integer not null,
+    created_at datetime not null,
+    updated_at datetime not null,
+    unique (id_clothing_size, measurement_type)
+);
+
+create index if not exists idx_clothing_size_measurement_type on clothing_size_measurement (measurement_type, measurement_min_mm, measurement_max_mm);
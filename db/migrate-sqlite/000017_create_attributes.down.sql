@@ -0,0 +1,4 @@
+drop index if exists idx_clothing_category_sub_attribute_value;
+drop table if exists clothing_category_sub_attribute;
+drop table if exists clothing_attribute_value;
+drop table if exists clothing_attribute;
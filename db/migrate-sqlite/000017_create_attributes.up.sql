@@ -0,0 +1,95 @@
+-- clothing_attribute contains the kinds of attributes a subcategory can be tagged with, e.g. color or material
+-- id contains the id for attribute
+-- attribute_code contains the code of the attribute used in filters, lowercase letters, digits and underscores limit to 32 characters
+-- attribute_name contains the display name of the attribute limit to 64 characters
+-- attribute_multiple contains 1 when a subcategory can have several values of the attribute, 0 for a single value
+-- attribute_status contains the status of the attribute: 1 = active, 2 = inactive
+-- created_at contains the date and time when the attribute is created
+-- updated_at contains the date and time when the attribute is updated
+create table if not exists clothing_attribute (
+    id integer primary key,
+    attribute_code text not null unique,
+    attribute_name text not null,
+    attribute_multiple integer not null default 1,
+    attribute_status integer not null default 1,
+    created_at datetime not null,
+    updated_at datetime not null
+);
+
+-- clothing_attribute_value contains the allowed values of an attribute
+-- id contains the id for attribute value
+-- id_clothing_attribute contains the id for the attribute
+-- attribute_value_code contains the code of the value used in filters, lowercase letters, digits and underscores limit to 32 characters
+-- attribute_value_name contains the display name of the value limit to 64 characters
+-- attribute_value_order contains the position of the value within the attribute
+-- attribute_value_status contains the status of the value: 1 = active, 2 = inactive
+-- created_at contains the date and time when the attribute value is created
+-- updated_at contains the date and time when the attribute value is updated
+create table if not exists clothing_attribute_value (
+    id integer primary key,
+    id_clothing_attribute integer not null REFERENCES clothing_attribute(id),
+    attribute_value_code text not null,
+    attribute_value_name text not null,
+    attribute_value_order integer not null default 0,
+    attribute_value_status integer not null default 1,
+    created_at datetime not null,
+    updated_at datetime not null,
+    unique (id_clothing_attribute, attribute_value_code)
+);
+
+-- clothing_category_sub_attribute contains the attribute values a subcategory is tagged with
+-- id_clothing_category_sub contains the id for the subcategory
+-- id_clothing_attribute_value contains the id for the attribute value
+-- created_at contains the date and time when the subcategory is tagged
+create table if not exists clothing_category_sub_attribute (
+    id_clothing_category_sub integer not null REFERENCES clothing_category_sub(id),
+    id_clothing_attribute_value integer not null REFERENCES clothing_attribute_value(id),
+    created_at datetime not null,
+    primary key (id_clothing_category_sub, id_clothing_attribute_value)
+);
+
+create index if not exists idx_clothing_category_sub_attribute_value on clothing_category_sub_attribute (id_clothing_attribute_value);
+
+INSERT INTO clothing_attribute (id, attribute_code, attribute_name, attribute_multiple, attribute_status, created_at, updated_at)
+VALUES (1, 'color', 'Color', 1, 1, datetime('now'), datetime('now')),
+    (2, 'material', 'Material', 1, 1, datetime('now'), datetime('now')),
+    (3, 'occasion', 'Occasion', 1, 1, datetime('now'), datetime('now')),
+    (4, 'gender', 'Gender', 0, 1, datetime('now'), datetime('now')),
+    (5, 'season', 'Season', 1, 1, datetime('now'), datetime('now')),
+    (6, 'age_range', 'Age range', 0, 1, datetime('now'), datetime('now'));
+
+INSERT INTO clothing_attribute_value (id_clothing_attribute, attribute_value_code, attribute_value_name, attribute_value_order, attribute_value_status, created_at, updated_at)
+VALUES (1, 'black', 'Black', 1, 1, datetime('now'), datetime('now')),
+    (1, 'white', 'White', 2, 1, datetime('now'), datetime('now')),
+    (1, 'red', 'Red', 3, 1, datetime('now'), datetime('now')),
+    (1, 'pink', 'Pink', 4, 1, datetime('now'), datetime('now')),
+    (1, 'blue', 'Blue', 5, 1, datetime('now'), datetime('now')),
+    (1, 'green', 'Green', 6, 1, datetime('now'), datetime('now')),
+    (1, 'yellow', 'Yellow', 7, 1, datetime('now'), datetime('now')),
+    (1, 'purple', 'Purple', 8, 1, datetime('now'), datetime('now')),
+    (1, 'gold', 'Gold', 9, 1, datetime('now'), datetime('now')),
+    (1, 'silver', 'Silver', 10, 1, datetime('now'), datetime('now')),
+    (2, 'cotton', 'Cotton', 1, 1, datetime('now'), datetime('now')),
+    (2, 'silk', 'Silk', 2, 1, datetime('now'), datetime('now')),
+    (2, 'satin', 'Satin', 3, 1, datetime('now'), datetime('now')),
+    (2, 'lace', 'Lace', 4, 1, datetime('now'), datetime('now')),
+    (2, 'linen', 'Linen', 5, 1, datetime('now'), datetime('now')),
+    (2, 'wool', 'Wool', 6, 1, datetime('now'), datetime('now')),
+    (2, 'polyester', 'Polyester', 7, 1, datetime('now'), datetime('now')),
+    (3, 'wedding', 'Wedding', 1, 1, datetime('now'), datetime('now')),
+    (3, 'party', 'Party', 2, 1, datetime('now'), datetime('now')),
+    (3, 'formal', 'Formal', 3, 1, datetime('now'), datetime('now')),
+    (3, 'traditional', 'Traditional', 4, 1, datetime('now'), datetime('now')),
+    (3, 'costume', 'Costume', 5, 1, datetime('now'), datetime('now')),
+    (3, 'casual', 'Casual', 6, 1, datetime('now'), datetime('now')),
+    (4, 'female', 'Female', 1, 1, datetime('now'), datetime('now')),
+    (4, 'male', 'Male', 2, 1, datetime('now'), datetime('now')),
+    (4, 'unisex', 'Unisex', 3, 1, datetime('now'), datetime('now')),
+    (5, 'spring', 'Spring', 1, 1, datetime('now'), datetime('now')),
+    (5, 'summer', 'Summer', 2, 1, datetime('now'), datetime('now')),
+    (5, 'autumn', 'Autumn', 3, 1, datetime('now'), datetime('now')),
+    (5, 'winter', 'Winter', 4, 1, datetime('now'), datetime('now')),
+    (6, 'baby', 'Baby (0-2 years)', 1, 1, datetime('now'), datetime('now')),
+    (6, 'kids', 'Kids (3-12 years)', 2, 1, datetime('now'), datetime('now')),
+    (6, 'teen', 'Teen (13-17 years)', 3, 1, datetime('now'), datetime('now')),
+    (6, 'adult', 'Adult', 4, 1, datetime('now'), datetime('now'));
@@ -0,0 +1,17 @@
+drop trigger if exists clothing_rental_fts_delete;
+drop trigger if exists clothing_rental_fts_update;
+drop trigger if exists clothing_rental_fts_insert;
+drop trigger if exists clothing_size_fts_update;
+drop trigger if exists clothing_customer_fts_delete;
+drop trigger if exists clothing_customer_fts_update;
+drop trigger if exists clothing_customer_fts_insert;
+drop trigger if exists clothing_category_sub_fts_delete;
+drop trigger if exists clothing_category_sub_fts_update;
+drop trigger if exists clothing_category_sub_fts_insert;
+drop trigger if exists clothing_category_fts_delete;
+drop trigger if exists clothing_category_fts_update;
+drop trigger if exists clothing_category_fts_insert;
+drop table if exists clothing_rental_fts;
+drop table if exists clothing_customer_fts;
+drop table if exists clothing_category_sub_fts;
+drop table if exists clothing_category_fts;
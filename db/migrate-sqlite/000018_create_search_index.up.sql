@@ -0,0 +1,130 @@
+-- clothing_category_fts contains the full-text index of the categories, the rowid is the id of the category
+-- clothes_cat_name contains the name of the category
+-- clothes_notes contains the notes of the category
+create virtual table if not exists clothing_category_fts using fts5(
+    clothes_cat_name,
+    clothes_notes,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+-- clothing_category_sub_fts contains the full-text index of the subcategories, the rowid is the id of the subcategory
+-- clothes_cat_name_sub contains the name of the subcategory
+-- clothes_cat_location_sub contains the location of the subcategory
+create virtual table if not exists clothing_category_sub_fts using fts5(
+    clothes_cat_name_sub,
+    clothes_cat_location_sub,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+-- clothing_customer_fts contains the full-text index of the customers, the rowid is the id of the customer
+-- cust_name contains the name of the customer
+-- cust_phone contains the phone number of the customer
+-- cust_email contains the email address of the customer
+create virtual table if not exists clothing_customer_fts using fts5(
+    cust_name,
+    cust_phone,
+    cust_email,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+-- clothing_rental_fts contains the full-text index of the rentals, the rowid is the id of the rental
+-- cust_name contains the name of the customer renting
+-- clothes_cat_name_sub contains the name of the rented subcategory
+-- clothes_size_name contains the name of the rented size
+create virtual table if not exists clothing_rental_fts using fts5(
+    cust_name,
+    clothes_cat_name_sub,
+    clothes_size_name,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+-- The triggers below keep the indexes in sync with their tables
+create trigger if not exists clothing_category_fts_insert after insert on clothing_category begin
+    insert into clothing_category_fts (rowid, clothes_cat_name, clothes_notes)
+    values (new.id, new.clothes_cat_name, coalesce(new.clothes_notes, ''));
+end;
+
+create trigger if not exists clothing_category_fts_update after update of clothes_cat_name, clothes_notes on clothing_category begin
+    update clothing_category_fts set clothes_cat_name = new.clothes_cat_name, clothes_notes = coalesce(new.clothes_notes, '')
+    where rowid = new.id;
+end;
+
+create trigger if not exists clothing_category_fts_delete after delete on clothing_category begin
+    delete from clothing_category_fts where rowid = old.id;
+end;
+
+create trigger if not exists clothing_category_sub_fts_insert after insert on clothing_category_sub begin
+    insert into clothing_category_sub_fts (rowid, clothes_cat_name_sub, clothes_cat_location_sub)
+    values (new.id, new.clothes_cat_name_sub, new.clothes_cat_location_sub);
+end;
+
+create trigger if not exists clothing_category_sub_fts_update after update of clothes_cat_name_sub, clothes_cat_location_sub on clothing_category_sub begin
+    update clothing_category_sub_fts set clothes_cat_name_sub = new.clothes_cat_name_sub, clothes_cat_location_sub = new.clothes_cat_location_sub
+    where rowid = new.id;
+    update clothing_rental_fts set clothes_cat_name_sub = new.clothes_cat_name_sub
+    where rowid in (select id from clothing_rental where id_clothing_category_sub = new.id);
+end;
+
+create trigger if not exists clothing_category_sub_fts_delete after delete on clothing_category_sub begin
+    delete from clothing_category_sub_fts where rowid = old.id;
+end;
+
+create trigger if not exists clothing_customer_fts_insert after insert on clothing_customer begin
+    insert into clothing_customer_fts (rowid, cust_name, cust_phone, cust_email)
+    values (new.id, new.cust_name, new.cust_phone, new.cust_email);
+end;
+
+create trigger if not exists clothing_customer_fts_update after update of cust_name, cust_phone, cust_email on clothing_customer begin
+    update clothing_customer_fts set cust_name = new.cust_name, cust_phone = new.cust_phone, cust_email = new.cust_email
+    where rowid = new.id;
+    update clothing_rental_fts set cust_name = new.cust_name
+    where rowid in (select id from clothing_rental where id_clothing_customer = new.id);
+end;
+
+create trigger if not exists clothing_customer_fts_delete after delete on clothing_customer begin
+    delete from clothing_customer_fts where rowid = old.id;
+end;
+
+create trigger if not exists clothing_size_fts_update after update of clothes_size_name on clothing_size begin
+    update clothing_rental_fts set clothes_size_name = new.clothes_size_name
+    where rowid in (select id from clothing_rental where id_clothing_size = new.id);
+end;
+
+create trigger if not exists clothing_rental_fts_insert after insert on clothing_rental begin
+    insert into clothing_rental_fts (rowid, cust_name, clothes_cat_name_sub, clothes_size_name)
+    values (
+        new.id,
+        coalesce((select cust_name from clothing_customer where id = new.id_clothing_customer), ''),
+        coalesce((select clothes_cat_name_sub from clothing_category_sub where id = new.id_clothing_category_sub), ''),
+        coalesce((select clothes_size_name from clothing_size where id = new.id_clothing_size), '')
+    );
+end;
+
+create trigger if not exists clothing_rental_fts_update after update of id_clothing_customer, id_clothing_category_sub, id_clothing_size on clothing_rental begin
+    update clothing_rental_fts set
+        cust_name = coalesce((select cust_name from clothing_customer where id = new.id_clothing_customer), ''),
+        clothes_cat_name_sub = coalesce((select clothes_cat_name_sub from clothing_category_sub where id = new.id_clothing_category_sub), ''),
+        clothes_size_name = coalesce((select clothes_size_name from clothing_size where id = new.id_clothing_size), '')
+    where rowid = new.id;
+end;
+
+create trigger if not exists clothing_rental_fts_delete after delete on clothing_rental begin
+    delete from clothing_rental_fts where rowid = old.id;
+end;
+
+-- Index the rows that already exist
+insert into clothing_category_fts (rowid, clothes_cat_name, clothes_notes)
+select id, clothes_cat_name, coalesce(clothes_notes, '') from clothing_category;
+
+insert into clothing_category_sub_fts (rowid, clothes_cat_name_sub, clothes_cat_location_sub)
+select id, clothes_cat_name_sub, clothes_cat_location_sub from clothing_category_sub;
+
+insert into clothing_customer_fts (rowid, cust_name, cust_phone, cust_email)
+select id, cust_name, cust_phone, cust_email from clothing_customer;
+
+insert into clothing_rental_fts (rowid, cust_name, clothes_cat_name_sub, clothes_size_name)
+select r.id, coalesce(cu.cust_name, ''), coalesce(s.clothes_cat_name_sub, ''), coalesce(z.clothes_size_name, '')
+from clothing_rental r
+left join clothing_customer cu on cu.id = r.id_clothing_customer
+left join clothing_category_sub s on s.id = r.id_clothing_category_sub
+left join clothing_size z on z.id = r.id_clothing_size;
@@ -0,0 +1,7 @@
+drop index if exists idx_clothing_rental_location;
+drop index if exists idx_clothing_inventory_movement_location;
+drop index if exists idx_clothing_category_sub_location;
+alter table clothing_rental drop column id_clothing_location;
+alter table clothing_inventory_movement drop column id_clothing_location;
+alter table clothing_category_sub drop column id_clothing_location;
+drop table if exists clothing_location;
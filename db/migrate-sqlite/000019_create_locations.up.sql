@@ -0,0 +1,59 @@
+-- clothing_location contains the stores and warehouses stock is kept at
+-- id contains the id for location
+-- location_name contains the name of the location limit to 64 characters, unique regardless of case
+-- location_type contains the type of the location: 1 = store, 2 = warehouse
+-- location_address contains the address of the location limit to 256 characters
+-- location_status contains the status of the location: 1 = active, 2 = inactive
+-- created_at contains the date and time when the location is created
+-- updated_at contains the date and time when the location is updated
+create table if not exists clothing_location (
+    id integer primary key,
+    location_name text not null unique collate nocase,
+    location_type integer not null default 1,
+    location_address text not null default '',
+    location_status integer not null default 1,
+    created_at datetime not null,
+    updated_at datetime not null
+);
+
+-- Every location the subcategories name becomes a store, spellings that only differ in case are merged
+INSERT INTO clothing_location (location_name, location_type, location_address, location_status, created_at, updated_at)
+SELECT trim(clothes_cat_location_sub), 1, '', 1, datetime('now'), datetime('now')
+FROM clothing_category_sub WHERE trim(clothes_cat_location_sub) != ''
+GROUP BY trim(clothes_cat_location_sub) COLLATE NOCASE;
+
+INSERT INTO clothing_location (location_name, location_type, location_address, location_status, created_at, updated_at)
+SELECT 'Unassigned', 1, '', 1, datetime('now'), datetime('now')
+WHERE EXISTS (SELECT 1 FROM clothing_category_sub WHERE trim(clothes_cat_location_sub) = '')
+    AND NOT EXISTS (SELECT 1 FROM clothing_location WHERE location_name = 'Unassigned');
+
+-- id_clothing_location contains the id for the location the subcategory is kept at,
+-- clothes_cat_location_sub keeps the name of that location
+alter table clothing_category_sub add column id_clothing_location integer;
+
+UPDATE clothing_category_sub SET id_clothing_location = (
+    SELECT l.id FROM clothing_location l
+    WHERE l.location_name = CASE WHEN trim(clothing_category_sub.clothes_cat_location_sub) = '' THEN 'Unassigned'
+                                 ELSE trim(clothing_category_sub.clothes_cat_location_sub) END
+);
+UPDATE clothing_category_sub SET clothes_cat_location_sub = (
+    SELECT l.location_name FROM clothing_location l WHERE l.id = clothing_category_sub.id_clothing_location
+);
+
+-- id_clothing_location contains the id for the location the stock moved in or out of
+alter table clothing_inventory_movement add column id_clothing_location integer;
+
+UPDATE clothing_inventory_movement SET id_clothing_location = (
+    SELECT s.id_clothing_location FROM clothing_category_sub s WHERE s.id = clothing_inventory_movement.id_clothing_category
+);
+
+-- id_clothing_location contains the id for the location the rental was handed out from
+alter table clothing_rental add column id_clothing_location integer;
+
+UPDATE clothing_rental SET id_clothing_location = (
+    SELECT s.id_clothing_location FROM clothing_category_sub s WHERE s.id = clothing_rental.id_clothing_category_sub
+);
+
+create index if not exists idx_clothing_category_sub_location on clothing_category_sub (id_clothing_location);
+create index if not exists idx_clothing_inventory_movement_location on clothing_inventory_movement (id_clothing_location, id_clothing_size);
+create index if not exists idx_clothing_rental_location on clothing_rental (id_clothing_location);
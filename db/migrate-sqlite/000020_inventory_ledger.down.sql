@@ -0,0 +1,5 @@
+drop index if exists idx_clothing_rental_size;
+drop index if exists idx_clothing_inventory_movement_size;
+
+UPDATE clothing_inventory_movement SET clothes_movement_action = 2 WHERE clothes_movement_action = 4;
+UPDATE clothing_inventory_movement SET clothes_movement_action = 1 WHERE clothes_movement_action = 3;
@@ -0,0 +1,11 @@
+-- Rentals booked their movements as 1 = BUY and returns as 2 = SELL. A purchase never moves stock out and a sale
+-- never moves it in, so those movements are recognisable and get 3 = RENT and 4 = RETURN.
+UPDATE clothing_inventory_movement SET clothes_movement_action = 3
+WHERE clothes_movement_action = 1 AND clothes_qty_in = 0 AND clothes_qty_out > 0;
+
+UPDATE clothing_inventory_movement SET clothes_movement_action = 4
+WHERE clothes_movement_action = 2 AND clothes_qty_in > 0 AND clothes_qty_out = 0;
+
+-- The stock of a size is summed from its movements and open rentals, see /api/inventory
+create index if not exists idx_clothing_inventory_movement_size on clothing_inventory_movement (id_clothing_size, clothes_cat_status_sub);
+create index if not exists idx_clothing_rental_size on clothing_rental (id_clothing_size, clothes_rent_status);
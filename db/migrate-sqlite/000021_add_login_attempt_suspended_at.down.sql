@@ -0,0 +1 @@
+alter table clothing_login_attempts drop column attempt_suspended_at;
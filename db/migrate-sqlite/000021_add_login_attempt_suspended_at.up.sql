@@ -0,0 +1,8 @@
+-- attempt_suspended_at contains the updated_at the lockout wrote to the users when it suspended them, null when it
+-- did not. The suspension is only lifted while updated_at is unchanged, so a later manual suspension stays.
+alter table clothing_login_attempts add column attempt_suspended_at datetime;
+
+-- Lockouts that are already running keep being lifted when they run out
+UPDATE clothing_login_attempts SET attempt_suspended_at = (
+    SELECT u.updated_at FROM clothing_users u WHERE u.username = clothing_login_attempts.attempt_key COLLATE NOCASE AND u.user_status = 3
+) WHERE attempt_key_type = 1 AND attempt_suspended_user = 1;
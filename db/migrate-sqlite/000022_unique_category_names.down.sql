@@ -0,0 +1,2 @@
+drop index if exists idx_clothing_category_sub_name;
+drop index if exists idx_clothing_category_name;
@@ -0,0 +1,19 @@
+-- Active category names are unique without case, subcategory names within their category. Names that are already
+-- taken twice get the id of the later row appended, so the indexes can be built without deactivating anything.
+UPDATE clothing_category SET clothes_cat_name = clothes_cat_name || ' (' || id || ')'
+WHERE clothes_cat_status = 1 AND EXISTS (
+    SELECT 1 FROM clothing_category o WHERE o.clothes_cat_status = 1 AND o.id < clothing_category.id
+    AND o.clothes_cat_name = clothing_category.clothes_cat_name COLLATE NOCASE
+);
+
+UPDATE clothing_category_sub SET clothes_cat_name_sub = clothes_cat_name_sub || ' (' || id || ')'
+WHERE clothes_cat_status_sub = 1 AND EXISTS (
+    SELECT 1 FROM clothing_category_sub o WHERE o.clothes_cat_status_sub = 1 AND o.id < clothing_category_sub.id
+    AND o.id_clothing_category = clothing_category_sub.id_clothing_category
+    AND o.clothes_cat_name_sub = clothing_category_sub.clothes_cat_name_sub COLLATE NOCASE
+);
+
+create unique index if not exists idx_clothing_category_name on clothing_category (clothes_cat_name collate nocase)
+where clothes_cat_status = 1;
+create unique index if not exists idx_clothing_category_sub_name on clothing_category_sub (id_clothing_category, clothes_cat_name_sub collate nocase)
+where clothes_cat_status_sub = 1;
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"clothingretail/db"
+	"clothingretail/models"
+	"clothingretail/utils"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// apiTokenPrefix marks bearer tokens issued by this application
+	apiTokenPrefix = "crt_"
+
+	// apiTokenBytes is the amount of random bytes behind every api token
+	apiTokenBytes = 32
+
+	// apiTokenPrefixLength is how much of the token is kept in clear text to recognise it
+	apiTokenPrefixLength = 12
+
+	authMethodSession = "session"
+	authMethodToken   = "token"
+)
+
+var errApiTokenInvalid = errors.New("api token is invalid, revoked or expired")
+
+// bearerToken extracts the token of an "Authorization: Bearer" header
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// lookupApiToken returns the active token behind a raw bearer token and records its use
+func lookupApiToken(raw string) (*models.ClothingApiToken, error) {
+	var token models.ClothingApiToken
+	var scopes string
+	var expiresAt sql.NullTime
+
+	err := db.DB.QueryRow(
+		`SELECT t.id, t.id_clothing_users, t.token_scopes, t.token_expires_at
+         FROM clothing_api_tokens t JOIN clothing_users u ON u.id = t.id_clothing_users
+         WHERE t.token_hash = ? AND t.token_status = ? AND u.user_status = ?`,
+		utils.HashToken(raw), utils.API_TOKEN_STATUS_ACTIVE, utils.CLOTHES_USER_STATUS_ACTIVE,
+	).Scan(&token.ID, &token.IDClothingUsers, &scopes, &expiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errApiTokenInvalid
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if expiresAt.Valid && now.After(expiresAt.Time) {
+		return nil, errApiTokenInvalid
+	}
+	token.TokenScopes = splitScopes(scopes)
+
+	if _, err := db.DB.Exec("UPDATE clothing_api_tokens SET token_last_used_at = ? WHERE id = ?", now, token.ID); err != nil {
+		log.Printf("Error recording api token use: %v", err)
+	}
+
+	return &token, nil
+}
+
+func splitScopes(scopes string) []string {
+	result := []string{}
+	for _, scope := range strings.Split(scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			result = append(result, scope)
+		}
+	}
+	return result
+}
+
+// tokenHasScope reports whether the request, when made with an api token, was granted a permission
+func tokenHasScope(c *gin.Context, permission string) bool {
+	if c.GetString("auth_method") != authMethodToken {
+		return true
+	}
+	for _, scope := range c.GetStringSlice("token_scopes") {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func scanApiToken(rows *sql.Rows) (models.ClothingApiToken, error) {
+	var token models.ClothingApiToken
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+
+	err := rows.Scan(&token.ID, &token.IDClothingUsers, &token.TokenName, &token.TokenPrefix, &scopes,
+		&token.TokenStatus, &expiresAt, &lastUsedAt, &token.CreatedAt, &token.UpdatedAt)
+	if err != nil {
+		return token, err
+	}
+
+	token.TokenScopes = splitScopes(scopes)
+	if expiresAt.Valid {
+		token.TokenExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.TokenLastUsedAt = &lastUsedAt.Time
+	}
+	return token, nil
+}
+
+// createApiToken issues a token for userID, limited to scopes the user's role actually has
+func createApiToken(c *gin.Context, userID int) {
+	var req models.CreateApiTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.TokenName = strings.TrimSpace(req.TokenName)
+	if req.TokenName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token name is required"})
+		return
+	}
+
+	var role int
+	err := db.DB.QueryRow(
+		"SELECT user_role FROM clothing_users WHERE id = ? AND user_status = ?",
+		userID, utils.CLOTHES_USER_STATUS_ACTIVE,
+	).Scan(&role)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	for _, scope := range req.TokenScopes {
+		if !utils.HasPermission(role, scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Scope not allowed for this user: " + scope})
+			return
+		}
+	}
+
+	random, err := utils.GenerateToken(apiTokenBytes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	raw := apiTokenPrefix + random
+
+	now := time.Now()
+	token := models.ClothingApiToken{
+		IDClothingUsers: userID,
+		TokenName:       req.TokenName,
+		TokenPrefix:     raw[:apiTokenPrefixLength],
+		TokenScopes:     req.TokenScopes,
+		TokenStatus:     utils.API_TOKEN_STATUS_ACTIVE,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		Token:           raw,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		token.TokenExpiresAt = &expiresAt
+	}
+
+	result, err := db.DB.Exec(
+		`INSERT INTO clothing_api_tokens (id_clothing_users, token_name, token_prefix, token_hash, token_scopes,
+         token_status, token_expires_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		token.IDClothingUsers, token.TokenName, token.TokenPrefix, utils.HashToken(raw),
+		strings.Join(token.TokenScopes, ","), token.TokenStatus, token.TokenExpiresAt, token.CreatedAt, token.UpdatedAt,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	token.ID = int(id)
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// listApiTokens returns every token of userID, newest first
+func listApiTokens(c *gin.Context, userID int) {
+	rows, err := db.DB.Query(
+		`SELECT id, id_clothing_users, token_name, token_prefix, token_scopes, token_status,
+         token_expires_at, token_last_used_at, created_at, updated_at
+         FROM clothing_api_tokens WHERE id_clothing_users = ? ORDER BY created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	tokens := []models.ClothingApiToken{}
+	for rows.Next() {
+		token, err := scanApiToken(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		tokens = append(tokens, token)
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateApiToken issues a personal api token for the authenticated user
+func CreateApiToken(c *gin.Context) {
+	// A token must not be able to mint further tokens
+	if c.GetString("auth_method") == authMethodToken {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API tokens can only be created from a logged in session"})
+		return
+	}
+	createApiToken(c, c.GetInt("user_id"))
+}
+
+// GetApiTokens lists the api tokens of the authenticated user
+func GetApiTokens(c *gin.Context) {
+	listApiTokens(c, c.GetInt("user_id"))
+}
+
+// CreateUserApiToken issues a service token for another user, used for scanner and script accounts
+func CreateUserApiToken(c *gin.Context) {
+	if c.GetString("auth_method") == authMethodToken {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API tokens can only be created from a logged in session"})
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	createApiToken(c, userID)
+}
+
+// GetUserApiTokens lists the api tokens of another user
+func GetUserApiTokens(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	listApiTokens(c, userID)
+}
+
+// RevokeApiToken revokes one of the caller's tokens, user managers may revoke any token
+func RevokeApiToken(c *gin.Context) {
+	id := c.Param("id")
+
+	var ownerID int
+	if err := db.DB.QueryRow("SELECT id_clothing_users FROM clothing_api_tokens WHERE id = ?", id).Scan(&ownerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+
+	if ownerID != c.GetInt("user_id") {
+		var role int
+		err := db.DB.QueryRow("SELECT user_role FROM clothing_users WHERE id = ?", c.GetInt("user_id")).Scan(&role)
+		if err != nil || !utils.HasPermission(role, utils.PERM_USER_MANAGE) || !tokenHasScope(c, utils.PERM_USER_MANAGE) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+			return
+		}
+	}
+
+	_, err := db.DB.Exec(
+		"UPDATE clothing_api_tokens SET token_status = ?, updated_at = ? WHERE id = ?",
+		utils.API_TOKEN_STATUS_REVOKED, time.Now(), id,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked successfully"})
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"clothingretail/db"
+	"clothingretail/models"
+	"clothingretail/utils"
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// auditMaxValueLength truncates long values such as pictures so the log stays small
+	auditMaxValueLength = 256
+
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// auditFields flattens an entity into its JSON fields, timestamps are left out since every change touches them
+func auditFields(entity interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if entity == nil || (reflect.ValueOf(entity).Kind() == reflect.Ptr && reflect.ValueOf(entity).IsNil()) {
+		return fields
+	}
+
+	raw, err := json.Marshal(entity)
+	if err != nil {
+		return fields
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return fields
+	}
+
+	delete(fields, "created_at")
+	delete(fields, "updated_at")
+	for key, value := range fields {
+		if str, ok := value.(string); ok && len(str) > auditMaxValueLength {
+			fields[key] = str[:auditMaxValueLength] + "..."
+		}
+	}
+	return fields
+}
+
+// auditDiff returns the fields that differ between before and after, either side may be nil
+func auditDiff(before, after interface{}) map[string]map[string]interface{} {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+	diff := map[string]map[string]interface{}{}
+
+	for key, value := range afterFields {
+		if old, ok := beforeFields[key]; !ok || !reflect.DeepEqual(old, value) {
+			diff[key] = map[string]interface{}{"before": beforeFields[key], "after": value}
+		}
+	}
+	for key, old := range beforeFields {
+		if _, ok := afterFields[key]; !ok {
+			diff[key] = map[string]interface{}{"before": old, "after": nil}
+		}
+	}
+	return diff
+}
+
+// recordAudit writes who did what to which entity. A failure is logged but never fails the request.
+func recordAudit(c *gin.Context, action int, entityType string, entityID int64, before, after interface{}) {
+	diff, err := json.Marshal(auditDiff(before, after))
+	if err != nil {
+		log.Printf("Error encoding audit diff for %s %d: %v", entityType, entityID, err)
+		diff = []byte("{}")
+	}
+
+	_, err = db.DB.Exec(
+		`INSERT INTO clothing_audit_log (id_clothing_users, audit_action, audit_entity_type, audit_entity_id,
+         audit_diff, audit_client_ip, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		c.GetInt("user_id"), action, entityType, entityID, string(diff), c.ClientIP(), time.Now(),
+	)
+	if err != nil {
+		log.Printf("Error writing audit log for %s %d: %v", entityType, entityID, err)
+	}
+}
+
+// GetAuditLog queries the audit log. Supported filters: user_id, action (id or name),
+// entity_type, entity_id, client_ip, date_from and date_to, plus limit and offset.
+func GetAuditLog(c *gin.Context) {
+	query := `SELECT a.id, a.id_clothing_users, COALESCE(u.username, ''), a.audit_action, a.audit_entity_type,
+              a.audit_entity_id, a.audit_diff, a.audit_client_ip, a.created_at
+              FROM clothing_audit_log a LEFT JOIN clothing_users u ON u.id = a.id_clothing_users WHERE 1=1`
+
+	var args []interface{}
+
+	if userID := c.Query("user_id"); userID != "" {
+		query += " AND a.id_clothing_users = ?"
+		args = append(args, userID)
+	}
+
+	if action := c.Query("action"); action != "" {
+		actionID, err := strconv.Atoi(action)
+		if err != nil {
+			actionID = utils.AuditActionTransReverse(action)
+		}
+		query += " AND a.audit_action = ?"
+		args = append(args, actionID)
+	}
+
+	if entityType := c.Query("entity_type"); entityType != "" {
+		query += " AND a.audit_entity_type = ?"
+		args = append(args, entityType)
+	}
+
+	if entityID := c.Query("entity_id"); entityID != "" {
+		query += " AND a.audit_entity_id = ?"
+		args = append(args, entityID)
+	}
+
+	if clientIP := c.Query("client_ip"); clientIP != "" {
+		query += " AND a.audit_client_ip = ?"
+		args = append(args, clientIP)
+	}
+
+	if dateFrom := c.Query("date_from"); dateFrom != "" {
+		from, err := parseLocalDateTime(dateFrom)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date_from format"})
+			return
+		}
+		query += " AND a.created_at >= ?"
+		args = append(args, from)
+	}
+
+	if dateTo := c.Query("date_to"); dateTo != "" {
+		to, err := parseLocalDateTime(dateTo)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date_to format"})
+			return
+		}
+		query += " AND a.created_at <= ?"
+		args = append(args, to)
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultAuditLimit)))
+	if err != nil || limit <= 0 || limit > maxAuditLimit {
+		limit = defaultAuditLimit
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	query += " ORDER BY a.created_at DESC, a.id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.ClothingAuditLog{}
+	for rows.Next() {
+		var entry models.ClothingAuditLog
+		var diff string
+
+		if err := rows.Scan(&entry.ID, &entry.IDClothingUsers, &entry.Username, &entry.AuditAction,
+			&entry.AuditEntityType, &entry.AuditEntityID, &diff, &entry.AuditClientIP, &entry.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		entry.AuditActionStr = utils.AuditActionTrans(entry.AuditAction)
+		entry.AuditDiff = json.RawMessage(diff)
+		entries = append(entries, entry)
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
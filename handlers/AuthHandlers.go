@@ -1,11 +1,19 @@
 package handlers
 
 import (
+	"clothingretail/conf"
 	"clothingretail/db"
 	"clothingretail/models"
+	"clothingretail/utils"
+	"crypto/subtle"
 	"database/sql"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -22,6 +30,28 @@ type LoginResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
 	UserID  int    `json:"user_id,omitempty"`
+
+	// MustChangePin tells the client to send the user to the change-PIN flow
+	MustChangePin bool `json:"must_change_pin,omitempty"`
+
+	// TotpRequired means the PIN was accepted and ChallengeToken has to be sent to /api/auth/login/totp with a code
+	TotpRequired   bool   `json:"totp_required,omitempty"`
+	ChallengeToken string `json:"challenge_token,omitempty"`
+}
+
+const (
+	// defaultAdminPin is only used when no initial admin PIN was configured, and has to be changed on first login
+	defaultAdminPin = "123456"
+
+	// adminPinEnv overrides the admin_pin config entry for the bootstrap admin
+	adminPinEnv = "CLOTHINGRETAIL_ADMIN_PIN"
+)
+
+// pinChangeAllowedPaths are reachable while the user still has to change their PIN
+var pinChangeAllowedPaths = map[string]bool{
+	"/change-pin":          true,
+	"/api/auth/change-pin": true,
+	"/api/auth/logout":     true,
 }
 
 // Login handles user authentication
@@ -36,33 +66,63 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Validate PIN is numeric
-	pin, err := strconv.Atoi(req.Pin)
+	// An expired lockout is lifted before the attempt is counted against a fresh record
+	if err := releaseExpiredLockout(req.Username); err != nil {
+		log.Printf("Error releasing lockout for %s: %v", req.Username, err)
+	}
+
+	// Refuse attempts while the username or client IP is throttled or locked out,
+	// otherwise the attempt counts as failed until the login succeeds
+	retryAfter, failedCounts, err := claimLoginAttempt(req.Username, c.ClientIP())
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to authenticate",
+		})
+		return
+	}
+	if retryAfter > 0 {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "Too many failed attempts, please try again later",
+			"retry_after": seconds,
+		})
+		return
+	}
+
+	// Validate PIN is numeric
+	if _, err := strconv.Atoi(req.Pin); err != nil {
+		lockOutLoginKeys(req.Username, c.ClientIP(), failedCounts)
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "Failed to authenticate",
 		})
 		return
 	}
 
-	// Query user from database
+	// Query user from database, the PIN itself is verified against its hash below
 	var user models.ClothingUser
-	query := `SELECT id, username, pin, user_status, created_at, updated_at 
+	var legacyPin int
+	query := `SELECT id, username, pin, pin_hash, user_status, must_change_pin, totp_enabled, created_at, updated_at 
 	          FROM clothing_users 
-	          WHERE username = ? AND pin = ? AND user_status = 1`
+	          WHERE username = ? AND user_status = 1`
 
-	err = db.DB.QueryRow(query, req.Username, pin).Scan(
+	err = db.DB.QueryRow(query, req.Username).Scan(
 		&user.ID,
 		&user.Username,
-		&user.Pin,
+		&legacyPin,
+		&user.PinHash,
 		&user.UserStatus,
+		&user.MustChangePin,
+		&user.TotpEnabled,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			// User not found or credentials don't match
+			// User not found, still spend the time of a hash comparison
+			utils.CheckPinDummy(req.Pin)
+			lockOutLoginKeys(req.Username, c.ClientIP(), failedCounts)
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Failed to authenticate",
 			})
@@ -75,6 +135,14 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	if !verifyUserPin(&user, legacyPin, req.Pin) {
+		lockOutLoginKeys(req.Username, c.ClientIP(), failedCounts)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Failed to authenticate",
+		})
+		return
+	}
+
 	// Check if user is active
 	if user.UserStatus != 1 {
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -83,37 +151,65 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// A PIN that is trivially guessable has to be replaced before the user can continue
+	if !user.MustChangePin && utils.IsWeakPin(req.Pin) {
+		if err := setMustChangePin(user.ID, true); err != nil {
+			log.Printf("Error flagging weak PIN for user %d: %v", user.ID, err)
+		}
+		user.MustChangePin = true
+	}
+
+	// Users with two-factor authentication get a short lived challenge instead of a session,
+	// the attempt keeps counting as failed until the second step succeeds
+	if user.TotpEnabled {
+		challenge, err := createLoginChallenge(user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to authenticate",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, LoginResponse{
+			Success:        true,
+			Message:        "Authentication code required",
+			TotpRequired:   true,
+			ChallengeToken: challenge,
+		})
+		return
+	}
+
 	// Authentication successful
-	// Set session cookie (simple implementation)
-	c.SetCookie(
-		"user_session",
-		strconv.Itoa(user.ID),
-		3600,  // Max age in seconds (1 hour)
-		"/",   // Path
-		"",    // Domain
-		false, // Secure
-		true,  // HttpOnly
-	)
+	clearLoginAttempts(req.Username, c.ClientIP())
+
+	// Start a server-side session and hand out its opaque token
+	token, err := createSession(c, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to authenticate",
+		})
+		return
+	}
+	setSessionCookie(c, token, int(sessionMaxAge().Seconds()))
 
 	c.JSON(http.StatusOK, LoginResponse{
-		Success: true,
-		Message: "Login successful",
-		UserID:  user.ID,
+		Success:       true,
+		Message:       "Login successful",
+		UserID:        user.ID,
+		MustChangePin: user.MustChangePin,
 	})
 }
 
 // Logout handles user logout
 func Logout(c *gin.Context) {
-	// Clear the session cookie
-	c.SetCookie(
-		"user_session",
-		"",
-		-1, // Max age -1 to delete
-		"/",
-		"",
-		false,
-		true,
-	)
+	// Invalidate the session server side before clearing the cookie
+	if token, err := c.Cookie(sessionCookieName); err == nil && token != "" {
+		if err := revokeSession(token); err != nil {
+			log.Printf("Error revoking session: %v", err)
+		}
+	}
+	setSessionCookie(c, "", -1)
+	setCsrfCookie(c, "", -1)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -121,38 +217,285 @@ func Logout(c *gin.Context) {
 	})
 }
 
-// AuthMiddleware checks if user is authenticated
+// abortUnauthenticated sends API callers a JSON 401 and browsers back to the login page
+func abortUnauthenticated(c *gin.Context) {
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, "/login")
+	c.Abort()
+}
+
+// pinChangePending keeps the user inside the change-PIN flow until a new PIN is picked, whether
+// they come in with a session or an api token. It reports whether the request was aborted.
+func pinChangePending(c *gin.Context, userID int) bool {
+	var mustChangePin bool
+	err := db.DB.QueryRow("SELECT must_change_pin FROM clothing_users WHERE id = ?", userID).Scan(&mustChangePin)
+	if err != nil {
+		abortUnauthenticated(c)
+		return true
+	}
+	if !mustChangePin || pinChangeAllowedPaths[c.Request.URL.Path] {
+		return false
+	}
+
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":           "PIN change required",
+			"must_change_pin": true,
+		})
+		return true
+	}
+	c.Redirect(http.StatusFound, "/change-pin")
+	c.Abort()
+	return true
+}
+
+// AuthMiddleware checks if user is authenticated, either through the session cookie
+// or, for /api routes, through an "Authorization: Bearer" api token
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		cookie, err := c.Cookie("user_session")
-		if err != nil || cookie == "" {
-			c.Redirect(http.StatusFound, "/login")
-			c.Abort()
+		if raw := bearerToken(c); raw != "" && strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			token, err := lookupApiToken(raw)
+			if err != nil {
+				abortUnauthenticated(c)
+				return
+			}
+
+			if pinChangePending(c, token.IDClothingUsers) {
+				return
+			}
+
+			// Set user ID and the token's scopes in context for use in handlers
+			c.Set("user_id", token.IDClothingUsers)
+			c.Set("auth_method", authMethodToken)
+			c.Set("api_token_id", token.ID)
+			c.Set("token_scopes", token.TokenScopes)
+			c.Next()
+			return
+		}
+
+		token, err := c.Cookie(sessionCookieName)
+		if err != nil || token == "" {
+			abortUnauthenticated(c)
 			return
 		}
 
-		// Validate session (check if user exists and is active)
-		userID, err := strconv.Atoi(cookie)
+		// Validate session (check if it exists, is not expired and its user is active)
+		session, err := lookupSession(token)
 		if err != nil {
-			c.Redirect(http.StatusFound, "/login")
-			c.Abort()
+			setSessionCookie(c, "", -1)
+			abortUnauthenticated(c)
 			return
 		}
 
-		var userStatus int
-		err = db.DB.QueryRow("SELECT user_status FROM clothing_users WHERE id = ?", userID).Scan(&userStatus)
-		if err != nil || userStatus != 1 {
-			c.Redirect(http.StatusFound, "/login")
-			c.Abort()
+		if err := renewSession(c, session, token); err != nil {
+			log.Printf("Error renewing session: %v", err)
+		}
+
+		if pinChangePending(c, session.IDClothingUsers) {
 			return
 		}
 
 		// Set user ID in context for use in handlers
-		c.Set("user_id", userID)
+		c.Set("user_id", session.IDClothingUsers)
+		c.Set("auth_method", authMethodSession)
+		c.Set("session_id", session.ID)
+		c.Next()
+	}
+}
+
+// callerPermitted reports whether the role of the authenticated user grants a permission and, for api
+// tokens, whether the token carries it as a scope. The role is returned so it can be kept in the context.
+func callerPermitted(c *gin.Context, permission string) (int, bool) {
+	var role int
+	err := db.DB.QueryRow(
+		"SELECT user_role FROM clothing_users WHERE id = ? AND user_status = ?",
+		c.GetInt("user_id"), utils.CLOTHES_USER_STATUS_ACTIVE,
+	).Scan(&role)
+	if err != nil {
+		return 0, false
+	}
+	return role, utils.HasPermission(role, permission) && tokenHasScope(c, permission)
+}
+
+// RequirePermission checks that the authenticated user's role grants the given permission,
+// and when the request uses an api token, that the token was scoped to it as well.
+// It must be layered after AuthMiddleware, which puts user_id into the context.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, ok := callerPermitted(c, permission)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "You do not have permission to perform this action",
+			})
+			return
+		}
+
+		c.Set("user_role", role)
 		c.Next()
 	}
 }
 
+// RequireSession refuses requests made with an api token. It guards the account security routes,
+// PIN, TOTP and sessions, so a leaked token can not be used to take over the account.
+// It must be layered after AuthMiddleware, which puts auth_method into the context.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") == authMethodToken {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "This action is only available from a logged in session",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ChangePin lets the authenticated user replace their own PIN
+func ChangePin(c *gin.Context) {
+	var req models.ChangePinRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "PIN must be exactly 6 digits"})
+		return
+	}
+
+	userID := c.GetInt("user_id")
+
+	var user models.ClothingUser
+	var legacyPin int
+	err := db.DB.QueryRow(
+		"SELECT id, pin, pin_hash FROM clothing_users WHERE id = ? AND user_status = ?",
+		userID, utils.CLOTHES_USER_STATUS_ACTIVE,
+	).Scan(&user.ID, &legacyPin, &user.PinHash)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to authenticate"})
+		return
+	}
+
+	if !verifyUserPin(&user, legacyPin, req.CurrentPin) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current PIN is incorrect"})
+		return
+	}
+
+	if req.NewPin == req.CurrentPin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New PIN must be different from the current PIN"})
+		return
+	}
+
+	if utils.IsWeakPin(req.NewPin) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "PIN is too weak, avoid repeated digits and sequences"})
+		return
+	}
+
+	if err := setUserPin(userID, req.NewPin); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := setMustChangePin(userID, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "PIN changed successfully",
+	})
+}
+
+// setMustChangePin sets or clears the forced PIN change flag of a user
+func setMustChangePin(userID int, mustChange bool) error {
+	_, err := db.DB.Exec(
+		"UPDATE clothing_users SET must_change_pin = ?, updated_at = ? WHERE id = ?",
+		mustChange, time.Now(), userID,
+	)
+	return err
+}
+
+// initialAdminPin returns the PIN for the bootstrap admin and whether it still has to be changed
+func initialAdminPin() (string, bool) {
+	pin := os.Getenv(adminPinEnv)
+	if pin == "" {
+		pin = conf.Koan.String(conf.RunMode + ".admin_pin")
+	}
+	if pin == "" {
+		return defaultAdminPin, true
+	}
+
+	if _, err := strconv.Atoi(pin); err != nil || len(pin) != 6 || utils.IsWeakPin(pin) {
+		log.Println("Warning: configured admin PIN must be 6 digits and not weak, falling back to a forced PIN change")
+		return defaultAdminPin, true
+	}
+
+	return pin, false
+}
+
+// verifyUserPin checks a PIN against the stored hash. Users that still only have the
+// legacy plaintext PIN are compared in constant time and upgraded to a hash on success.
+func verifyUserPin(user *models.ClothingUser, legacyPin int, pin string) bool {
+	if user.PinHash != "" {
+		return utils.CheckPin(user.PinHash, pin)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(fmt.Sprintf("%06d", legacyPin)), []byte(pin)) != 1 {
+		return false
+	}
+
+	if err := setUserPin(user.ID, pin); err != nil {
+		log.Printf("Error upgrading PIN hash for user %d: %v", user.ID, err)
+	}
+	return true
+}
+
+// setUserPin stores the hash of a new PIN and clears the legacy plaintext column
+func setUserPin(userID int, pin string) error {
+	hash, err := utils.HashPin(pin)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.DB.Exec(
+		"UPDATE clothing_users SET pin = 0, pin_hash = ?, updated_at = ? WHERE id = ?",
+		hash, time.Now(), userID,
+	)
+	return err
+}
+
+// MigrateUserPins hashes every PIN that is still stored in plaintext
+func MigrateUserPins() error {
+	rows, err := db.DB.Query("SELECT id, pin FROM clothing_users WHERE pin_hash = ''")
+	if err != nil {
+		return err
+	}
+
+	legacyPins := map[int]int{}
+	for rows.Next() {
+		var id, pin int
+		if err := rows.Scan(&id, &pin); err != nil {
+			rows.Close()
+			return err
+		}
+		legacyPins[id] = pin
+	}
+	rows.Close()
+
+	for id, pin := range legacyPins {
+		if err := setUserPin(id, fmt.Sprintf("%06d", pin)); err != nil {
+			return err
+		}
+	}
+
+	if len(legacyPins) > 0 {
+		log.Printf("Hashed %d plaintext PINs", len(legacyPins))
+	}
+	return nil
+}
+
 // CreateDefaultUser creates a default admin user if no users exist
 func CreateDefaultUser() error {
 	var count int
@@ -162,12 +505,18 @@ func CreateDefaultUser() error {
 	}
 
 	if count == 0 {
+		pin, mustChangePin := initialAdminPin()
+		pinHash, err := utils.HashPin(pin)
+		if err != nil {
+			return err
+		}
+
 		// Create default admin user
-		query := `INSERT INTO clothing_users (username, pin, user_status, created_at, updated_at)
-		          VALUES (?, ?, ?, ?, ?)`
+		query := `INSERT INTO clothing_users (username, pin, pin_hash, user_status, user_role, must_change_pin, created_at, updated_at)
+		          VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 
 		now := time.Now()
-		_, err = db.DB.Exec(query, "admin", 123456, 1, now, now)
+		_, err = db.DB.Exec(query, "admin", 0, pinHash, 1, utils.USER_ROLE_OWNER, mustChangePin, now, now)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,626 @@
+package handlers
+
+import (
+	"clothingretail/db"
+	"clothingretail/models"
+	"clothingretail/utils"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attributeCodePattern restricts attribute and value codes to what reads well in a query string
+var attributeCodePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// loadAttributeValues reads the values of an attribute in display order, inactive ones only when asked for
+func loadAttributeValues(attributeID int, activeOnly bool) ([]models.ClothingAttributeValue, error) {
+	query := `SELECT id, id_clothing_attribute, attribute_value_code, attribute_value_name, attribute_value_order,
+              attribute_value_status, created_at, updated_at FROM clothing_attribute_value WHERE id_clothing_attribute = ?`
+	args := []interface{}{attributeID}
+	if activeOnly {
+		query += " AND attribute_value_status = ?"
+		args = append(args, utils.ATTRIBUTE_STATUS_ACTIVE)
+	}
+	query += " ORDER BY attribute_value_order, id"
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []models.ClothingAttributeValue{}
+	for rows.Next() {
+		var value models.ClothingAttributeValue
+		if err := rows.Scan(&value.ID, &value.IDClothingAttribute, &value.AttributeValueCode, &value.AttributeValueName,
+			&value.AttributeValueOrder, &value.AttributeValueStatus, &value.CreatedAt, &value.UpdatedAt); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// loadActiveAttributes reads the active attributes with their active values
+func loadActiveAttributes() ([]models.ClothingAttribute, error) {
+	rows, err := db.DB.Query(
+		`SELECT id, attribute_code, attribute_name, attribute_multiple, attribute_status, created_at, updated_at
+         FROM clothing_attribute WHERE attribute_status = ? ORDER BY id`,
+		utils.ATTRIBUTE_STATUS_ACTIVE,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := []models.ClothingAttribute{}
+	for rows.Next() {
+		var attribute models.ClothingAttribute
+		if err := rows.Scan(&attribute.ID, &attribute.AttributeCode, &attribute.AttributeName, &attribute.AttributeMultiple,
+			&attribute.AttributeStatus, &attribute.CreatedAt, &attribute.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		attributes = append(attributes, attribute)
+	}
+	rows.Close()
+
+	for i := range attributes {
+		attributes[i].Values, err = loadAttributeValues(attributes[i].ID, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return attributes, nil
+}
+
+// loadAttribute reads a single attribute with all its values, whatever their status
+func loadAttribute(id string) (*models.ClothingAttribute, error) {
+	var attribute models.ClothingAttribute
+	err := db.DB.QueryRow(
+		`SELECT id, attribute_code, attribute_name, attribute_multiple, attribute_status, created_at, updated_at
+         FROM clothing_attribute WHERE id = ?`,
+		id,
+	).Scan(&attribute.ID, &attribute.AttributeCode, &attribute.AttributeName, &attribute.AttributeMultiple,
+		&attribute.AttributeStatus, &attribute.CreatedAt, &attribute.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	attribute.Values, err = loadAttributeValues(attribute.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	return &attribute, nil
+}
+
+// loadSubAttributes reads the active attribute values of subcategories as value codes keyed by attribute code.
+// Every requested subcategory gets a map, empty when it is not tagged.
+func loadSubAttributes(subIDs []int) (map[int]map[string][]string, error) {
+	result := make(map[int]map[string][]string, len(subIDs))
+	if len(subIDs) == 0 {
+		return result, nil
+	}
+
+	args := make([]interface{}, 0, len(subIDs)+2)
+	args = append(args, utils.ATTRIBUTE_STATUS_ACTIVE, utils.ATTRIBUTE_STATUS_ACTIVE)
+	for _, id := range subIDs {
+		args = append(args, id)
+		result[id] = map[string][]string{}
+	}
+
+	rows, err := db.DB.Query(
+		`SELECT sa.id_clothing_category_sub, a.attribute_code, v.attribute_value_code
+         FROM clothing_category_sub_attribute sa
+         JOIN clothing_attribute_value v ON v.id = sa.id_clothing_attribute_value
+         JOIN clothing_attribute a ON a.id = v.id_clothing_attribute
+         WHERE a.attribute_status = ? AND v.attribute_value_status = ?
+         AND sa.id_clothing_category_sub IN (?`+strings.Repeat(", ?", len(subIDs)-1)+`)
+         ORDER BY a.id, v.attribute_value_order, v.id`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var subID int
+		var attributeCode, valueCode string
+		if err := rows.Scan(&subID, &attributeCode, &valueCode); err != nil {
+			return nil, err
+		}
+		result[subID][attributeCode] = append(result[subID][attributeCode], valueCode)
+	}
+	return result, rows.Err()
+}
+
+// parseAttributeFilters reads attr[code]=value1,value2 query parameters. Values of one attribute are
+// alternatives, different attributes must all match. Unknown attributes are refused.
+func parseAttributeFilters(c *gin.Context, attributes []models.ClothingAttribute) (map[string][]string, error) {
+	known := map[string]bool{}
+	for _, attribute := range attributes {
+		known[attribute.AttributeCode] = true
+	}
+
+	filters := map[string][]string{}
+	for code, raw := range c.QueryMap("attr") {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if !known[code] {
+			return nil, fmt.Errorf("Unknown attribute %s", code)
+		}
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+				filters[code] = append(filters[code], value)
+			}
+		}
+	}
+	return filters, nil
+}
+
+// attributeFilterClause turns attribute filters into conditions on clothing_category_sub.id,
+// leaving out the attribute skip so facet counts of an attribute ignore its own selection
+func attributeFilterClause(filters map[string][]string, skip string) (string, []interface{}) {
+	codes := make([]string, 0, len(filters))
+	for code := range filters {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	var clause strings.Builder
+	var args []interface{}
+	for _, code := range codes {
+		values := filters[code]
+		if code == skip || len(values) == 0 {
+			continue
+		}
+		clause.WriteString(` AND id IN (SELECT sa.id_clothing_category_sub FROM clothing_category_sub_attribute sa
+             JOIN clothing_attribute_value v ON v.id = sa.id_clothing_attribute_value
+             JOIN clothing_attribute a ON a.id = v.id_clothing_attribute
+             WHERE a.attribute_code = ? AND v.attribute_value_status = ? AND v.attribute_value_code IN (?` +
+			strings.Repeat(", ?", len(values)-1) + `))`)
+		args = append(args, code, utils.ATTRIBUTE_STATUS_ACTIVE)
+		for _, value := range values {
+			args = append(args, value)
+		}
+	}
+	return clause.String(), args
+}
+
+// attributeFacets counts the subcategories matching conditions per attribute value. The counts of an attribute
+// apply all filters except its own, so the other values of an attribute being filtered on keep their counts.
+func attributeFacets(conditions string, conditionArgs []interface{}, filters map[string][]string, attributes []models.ClothingAttribute) ([]models.AttributeFacet, error) {
+	facets := []models.AttributeFacet{}
+
+	for _, attribute := range attributes {
+		subQuery := "SELECT id FROM clothing_category_sub WHERE 1=1" + conditions
+		args := append([]interface{}{}, conditionArgs...)
+		clause, clauseArgs := attributeFilterClause(filters, attribute.AttributeCode)
+		subQuery += clause
+		args = append(args, clauseArgs...)
+		args = append(args, attribute.ID, utils.ATTRIBUTE_STATUS_ACTIVE)
+
+		rows, err := db.DB.Query(
+			`SELECT v.attribute_value_code, v.attribute_value_name, COUNT(sa.id_clothing_category_sub)
+             FROM clothing_attribute_value v
+             LEFT JOIN clothing_category_sub_attribute sa ON sa.id_clothing_attribute_value = v.id
+                 AND sa.id_clothing_category_sub IN (`+subQuery+`)
+             WHERE v.id_clothing_attribute = ? AND v.attribute_value_status = ?
+             GROUP BY v.id ORDER BY v.attribute_value_order, v.id`,
+			args...,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		facet := models.AttributeFacet{
+			AttributeCode: attribute.AttributeCode,
+			AttributeName: attribute.AttributeName,
+			Values:        []models.AttributeFacetValue{},
+		}
+		for rows.Next() {
+			var value models.AttributeFacetValue
+			if err := rows.Scan(&value.AttributeValueCode, &value.AttributeValueName, &value.Count); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			facet.Values = append(facet.Values, value)
+		}
+		rows.Close()
+
+		facets = append(facets, facet)
+	}
+	return facets, nil
+}
+
+// validateAttributeRequest normalises the codes of the request and checks them.
+// It returns the http status and message to report, or 0 when the request is valid.
+func validateAttributeRequest(req *models.AttributeRequest, excludeID int) (int, string) {
+	req.AttributeCode = strings.ToLower(strings.TrimSpace(req.AttributeCode))
+	req.AttributeName = strings.TrimSpace(req.AttributeName)
+
+	if !attributeCodePattern.MatchString(req.AttributeCode) {
+		return http.StatusBadRequest, "Attribute code may only contain lowercase letters, digits and underscores"
+	}
+	if req.AttributeName == "" {
+		return http.StatusBadRequest, "Attribute name is required"
+	}
+
+	seen := map[string]bool{}
+	for i := range req.Values {
+		value := &req.Values[i]
+		value.AttributeValueCode = strings.ToLower(strings.TrimSpace(value.AttributeValueCode))
+		value.AttributeValueName = strings.TrimSpace(value.AttributeValueName)
+
+		if !attributeCodePattern.MatchString(value.AttributeValueCode) {
+			return http.StatusBadRequest, "Value code may only contain lowercase letters, digits and underscores"
+		}
+		if value.AttributeValueName == "" {
+			return http.StatusBadRequest, "Value name is required"
+		}
+		if seen[value.AttributeValueCode] {
+			return http.StatusBadRequest, fmt.Sprintf("Value %s appears more than once", value.AttributeValueCode)
+		}
+		seen[value.AttributeValueCode] = true
+	}
+
+	var duplicates int
+	err := db.DB.QueryRow(
+		"SELECT COUNT(*) FROM clothing_attribute WHERE attribute_code = ? AND id != ?",
+		req.AttributeCode, excludeID,
+	).Scan(&duplicates)
+	if err != nil {
+		log.Printf("Error checking attribute code: %v", err)
+		return http.StatusInternalServerError, "Failed to validate attribute"
+	}
+	if duplicates > 0 {
+		return http.StatusConflict, "Attribute code already exists"
+	}
+
+	return 0, ""
+}
+
+// GetAttributes retrieves the active attributes with their active values
+func GetAttributes(c *gin.Context) {
+	attributes, err := loadActiveAttributes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, attributes)
+}
+
+// GetAttributeByID retrieves a single attribute with all its values
+func GetAttributeByID(c *gin.Context) {
+	attribute, err := loadAttribute(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attribute not found"})
+		return
+	}
+	c.JSON(http.StatusOK, attribute)
+}
+
+// CreateAttribute adds an attribute with its values, the order of the values is the display order
+func CreateAttribute(c *gin.Context) {
+	var req models.AttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status, message := validateAttributeRequest(&req, 0); status != 0 {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.Exec(
+		`INSERT INTO clothing_attribute (attribute_code, attribute_name, attribute_multiple, attribute_status,
+         created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		req.AttributeCode, req.AttributeName, req.AttributeMultiple, utils.ATTRIBUTE_STATUS_ACTIVE, now, now,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("Error inserting attribute: %v", err)
+		return
+	}
+	attributeID, _ := result.LastInsertId()
+
+	for i, value := range req.Values {
+		_, err = tx.Exec(
+			`INSERT INTO clothing_attribute_value (id_clothing_attribute, attribute_value_code, attribute_value_name,
+             attribute_value_order, attribute_value_status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			attributeID, value.AttributeValueCode, value.AttributeValueName, i+1, utils.ATTRIBUTE_STATUS_ACTIVE, now, now,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			log.Printf("Error inserting attribute value: %v", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	attribute, _ := loadAttribute(strconv.FormatInt(attributeID, 10))
+	recordAudit(c, utils.AUDIT_ACTION_CREATE, utils.AUDIT_ENTITY_ATTRIBUTE, attributeID, nil, attribute)
+
+	c.JSON(http.StatusCreated, attribute)
+}
+
+// UpdateAttribute renames an attribute and replaces its values. Values are matched by code, values
+// missing from the request are deactivated and taken off every subcategory.
+func UpdateAttribute(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.AttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, err := loadAttribute(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attribute not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status, message := validateAttributeRequest(&req, before.ID); status != 0 {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+	if req.AttributeCode != before.AttributeCode {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Attribute code cannot be changed"})
+		return
+	}
+
+	// Going from several values to a single one would leave subcategories with too many
+	if before.AttributeMultiple && !req.AttributeMultiple {
+		var crowded int
+		err = db.DB.QueryRow(
+			`SELECT COUNT(*) FROM (SELECT sa.id_clothing_category_sub FROM clothing_category_sub_attribute sa
+             JOIN clothing_attribute_value v ON v.id = sa.id_clothing_attribute_value
+             WHERE v.id_clothing_attribute = ? AND v.attribute_value_status = ?
+             GROUP BY sa.id_clothing_category_sub HAVING COUNT(*) > 1)`,
+			before.ID, utils.ATTRIBUTE_STATUS_ACTIVE,
+		).Scan(&crowded)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if crowded > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%d subcategories have several values for this attribute", crowded)})
+			return
+		}
+	}
+
+	existing := map[string]models.ClothingAttributeValue{}
+	for _, value := range before.Values {
+		existing[value.AttributeValueCode] = value
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.Exec(
+		"UPDATE clothing_attribute SET attribute_name = ?, attribute_multiple = ?, updated_at = ? WHERE id = ?",
+		req.AttributeName, req.AttributeMultiple, now, before.ID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("Error updating attribute: %v", err)
+		return
+	}
+
+	kept := map[string]bool{}
+	for i, value := range req.Values {
+		kept[value.AttributeValueCode] = true
+
+		if current, ok := existing[value.AttributeValueCode]; ok {
+			_, err = tx.Exec(
+				`UPDATE clothing_attribute_value SET attribute_value_name = ?, attribute_value_order = ?,
+                 attribute_value_status = ?, updated_at = ? WHERE id = ?`,
+				value.AttributeValueName, i+1, utils.ATTRIBUTE_STATUS_ACTIVE, now, current.ID,
+			)
+		} else {
+			_, err = tx.Exec(
+				`INSERT INTO clothing_attribute_value (id_clothing_attribute, attribute_value_code, attribute_value_name,
+                 attribute_value_order, attribute_value_status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				before.ID, value.AttributeValueCode, value.AttributeValueName, i+1, utils.ATTRIBUTE_STATUS_ACTIVE, now, now,
+			)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			log.Printf("Error saving attribute value: %v", err)
+			return
+		}
+	}
+
+	for code, value := range existing {
+		if kept[code] || value.AttributeValueStatus != utils.ATTRIBUTE_STATUS_ACTIVE {
+			continue
+		}
+		_, err = tx.Exec(
+			"UPDATE clothing_attribute_value SET attribute_value_status = ?, updated_at = ? WHERE id = ?",
+			utils.ATTRIBUTE_STATUS_INACTIVE, now, value.ID,
+		)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM clothing_category_sub_attribute WHERE id_clothing_attribute_value = ?", value.ID)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			log.Printf("Error removing attribute value: %v", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	after, _ := loadAttribute(id)
+	recordAudit(c, utils.AUDIT_ACTION_UPDATE, utils.AUDIT_ENTITY_ATTRIBUTE, int64(before.ID), before, after)
+
+	c.JSON(http.StatusOK, after)
+}
+
+// DeleteAttribute deactivates an attribute. Subcategories keep their values, which are hidden until
+// the attribute is active again.
+func DeleteAttribute(c *gin.Context) {
+	id := c.Param("id")
+
+	before, err := loadAttribute(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attribute not found"})
+		return
+	}
+
+	_, err = db.DB.Exec(
+		"UPDATE clothing_attribute SET attribute_status = ?, updated_at = ? WHERE id = ?",
+		utils.ATTRIBUTE_STATUS_INACTIVE, time.Now(), before.ID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("Error deactivating attribute: %v", err)
+		return
+	}
+
+	after, _ := loadAttribute(id)
+	recordAudit(c, utils.AUDIT_ACTION_DELETE, utils.AUDIT_ENTITY_ATTRIBUTE, int64(before.ID), before, after)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attribute deactivated successfully"})
+}
+
+// SetCategorySubAttributes replaces the attribute values of a subcategory. Attributes left out of the
+// request are cleared, values of inactive attributes are kept.
+func SetCategorySubAttributes(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.CategorySubAttributesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, err := loadCategorySub(id)
+	if err != nil || before.ClothesCatStatusSub != utils.CAT_SUB_STATUS_ACTIVE {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subcategory not found"})
+		return
+	}
+
+	attributes, err := loadActiveAttributes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	byCode := map[string]models.ClothingAttribute{}
+	for _, attribute := range attributes {
+		byCode[attribute.AttributeCode] = attribute
+	}
+
+	var valueIDs []int
+	for code, valueCodes := range req.Attributes {
+		attribute, ok := byCode[strings.ToLower(strings.TrimSpace(code))]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown attribute %s", code)})
+			return
+		}
+
+		chosen := map[int]bool{}
+		for _, valueCode := range valueCodes {
+			valueCode = strings.ToLower(strings.TrimSpace(valueCode))
+			valueID := 0
+			for _, value := range attribute.Values {
+				if value.AttributeValueCode == valueCode {
+					valueID = value.ID
+				}
+			}
+			if valueID == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown value %s for attribute %s", valueCode, attribute.AttributeCode)})
+				return
+			}
+			if !chosen[valueID] {
+				chosen[valueID] = true
+				valueIDs = append(valueIDs, valueID)
+			}
+		}
+
+		if !attribute.AttributeMultiple && len(chosen) > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Attribute %s takes a single value", attribute.AttributeCode)})
+			return
+		}
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`DELETE FROM clothing_category_sub_attribute WHERE id_clothing_category_sub = ?
+         AND id_clothing_attribute_value IN (SELECT v.id FROM clothing_attribute_value v
+             JOIN clothing_attribute a ON a.id = v.id_clothing_attribute WHERE a.attribute_status = ?)`,
+		before.ID, utils.ATTRIBUTE_STATUS_ACTIVE,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	for _, valueID := range valueIDs {
+		_, err = tx.Exec(
+			"INSERT INTO clothing_category_sub_attribute (id_clothing_category_sub, id_clothing_attribute_value, created_at) VALUES (?, ?, ?)",
+			before.ID, valueID, now,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			log.Printf("Error tagging subcategory: %v", err)
+			return
+		}
+	}
+
+	_, err = tx.Exec("UPDATE clothing_category_sub SET updated_at = ? WHERE id = ?", now, before.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	after, _ := loadCategorySub(id)
+	recordAudit(c, utils.AUDIT_ACTION_UPDATE, utils.AUDIT_ENTITY_CATEGORY_SUB, int64(before.ID), before, after)
+
+	c.JSON(http.StatusOK, after)
+}
@@ -3,7 +3,10 @@ package handlers
 import (
 	"clothingretail/db"
 	"clothingretail/models"
+	"clothingretail/utils"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,13 +14,15 @@ import (
 
 // CreateCategory handles creating a new clothing category
 func CreateCategory(c *gin.Context) {
-	var category models.ClothingCategory
-
-	if err := c.ShouldBindJSON(&category); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req models.ClothingCategoryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if !validateCategoryRequest(c, &req, 0) {
 		return
 	}
 
+	category := models.ClothingCategory{ClothesCatName: req.ClothesCatName, ClothesNotes: req.ClothesNotes}
 	category.CreatedAt = time.Now()
 	category.UpdatedAt = time.Now()
 	category.ClothesCatStatus = 1
@@ -27,6 +32,10 @@ func CreateCategory(c *gin.Context) {
 		category.ClothesCatName, category.ClothesNotes, category.ClothesCatStatus, category.CreatedAt, category.UpdatedAt,
 	)
 
+	if isUniqueViolation(err) {
+		respondFieldErrors(c, http.StatusConflict, fieldErrors{"clothes_cat_name": "already exists"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -35,19 +44,74 @@ func CreateCategory(c *gin.Context) {
 	id, _ := result.LastInsertId()
 	category.ID = int(id)
 
+	recordAudit(c, utils.AUDIT_ACTION_CREATE, utils.AUDIT_ENTITY_CATEGORY, id, nil, category)
+
 	c.JSON(http.StatusCreated, category)
 }
 
-// GetCategories retrieves all clothing categories
+// validateCategoryRequest trims the request and checks the name is not taken by another active category,
+// compared without case. It reports the failing fields itself and returns false when the request is refused.
+func validateCategoryRequest(c *gin.Context, req *models.ClothingCategoryRequest, excludeID int) bool {
+	req.ClothesCatName = strings.TrimSpace(req.ClothesCatName)
+	req.ClothesNotes = strings.TrimSpace(req.ClothesNotes)
+
+	var duplicates int
+	err := db.DB.QueryRow(
+		`SELECT COUNT(*) FROM clothing_category WHERE clothes_cat_name = ? COLLATE NOCASE
+         AND clothes_cat_status = ? AND id != ?`,
+		req.ClothesCatName, utils.CAT_STATUS_ACTIVE, excludeID,
+	).Scan(&duplicates)
+	if err != nil {
+		log.Printf("Error checking category name: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate category"})
+		return false
+	}
+	if duplicates > 0 {
+		respondFieldErrors(c, http.StatusConflict, fieldErrors{"clothes_cat_name": "already exists"})
+		return false
+	}
+
+	return true
+}
+
+// categoryListSpec lists the fields GetCategories sorts and filters on
+var categoryListSpec = listSpec{
+	fields: map[string]listField{
+		"id":                 {"id", listFieldInt},
+		"clothes_cat_name":   {"clothes_cat_name", listFieldText},
+		"clothes_cat_status": {"clothes_cat_status", listFieldInt},
+		"created_at":         {"created_at", listFieldTime},
+		"updated_at":         {"updated_at", listFieldTime},
+	},
+	defaultSort:   "clothes_cat_name",
+	statusField:   "clothes_cat_status",
+	statusDefault: utils.CAT_STATUS_ACTIVE,
+	statusNames:   utils.CatTransReverse,
+}
+
+// GetCategories retrieves the active clothing categories a page at a time, see listSpec for the parameters
 func GetCategories(c *gin.Context) {
-	rows, err := db.DB.Query("SELECT id, clothes_cat_name, clothes_notes, clothes_cat_status, created_at, updated_at FROM clothing_category WHERE clothes_cat_status = 1")
+	list, err := parseListQuery(c, categoryListSpec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, args, err := list.listAll(c,
+		"SELECT id, clothes_cat_name, clothes_notes, clothes_cat_status, created_at, updated_at FROM clothing_category WHERE 1=1", nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := db.DB.Query(query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	defer rows.Close()
 
-	var categories []models.ClothingCategory
+	categories := []models.ClothingCategory{}
 	for rows.Next() {
 		var category models.ClothingCategory
 		if err := rows.Scan(&category.ID, &category.ClothesCatName, &category.ClothesNotes, &category.ClothesCatStatus, &category.CreatedAt, &category.UpdatedAt); err != nil {
@@ -60,9 +124,8 @@ func GetCategories(c *gin.Context) {
 	c.JSON(http.StatusOK, categories)
 }
 
-// GetCategoryByID retrieves a single category by ID
-func GetCategoryByID(c *gin.Context) {
-	id := c.Param("id")
+// loadCategory reads a single category by ID
+func loadCategory(id string) (*models.ClothingCategory, error) {
 	var category models.ClothingCategory
 
 	err := db.DB.QueryRow(
@@ -70,6 +133,15 @@ func GetCategoryByID(c *gin.Context) {
 		id,
 	).Scan(&category.ID, &category.ClothesCatName, &category.ClothesNotes, &category.ClothesCatStatus, &category.CreatedAt, &category.UpdatedAt)
 
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// GetCategoryByID retrieves a single category by ID
+func GetCategoryByID(c *gin.Context) {
+	category, err := loadCategory(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
 		return
@@ -81,25 +153,38 @@ func GetCategoryByID(c *gin.Context) {
 // UpdateCategory updates an existing category
 func UpdateCategory(c *gin.Context) {
 	id := c.Param("id")
-	var category models.ClothingCategory
+	var req models.ClothingCategoryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
 
-	if err := c.ShouldBindJSON(&category); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	before, err := loadCategory(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
 		return
 	}
 
-	category.UpdatedAt = time.Now()
+	if !validateCategoryRequest(c, &req, before.ID) {
+		return
+	}
 
-	_, err := db.DB.Exec(
+	_, err = db.DB.Exec(
 		"UPDATE clothing_category SET clothes_cat_name = ?, clothes_notes = ?, updated_at = ? WHERE id = ?",
-		category.ClothesCatName, category.ClothesNotes, category.UpdatedAt, id,
+		req.ClothesCatName, req.ClothesNotes, time.Now(), id,
 	)
 
+	if isUniqueViolation(err) {
+		respondFieldErrors(c, http.StatusConflict, fieldErrors{"clothes_cat_name": "already exists"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	after, _ := loadCategory(id)
+	recordAudit(c, utils.AUDIT_ACTION_UPDATE, utils.AUDIT_ENTITY_CATEGORY, int64(before.ID), before, after)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Category updated successfully"})
 }
 
@@ -107,7 +192,13 @@ func UpdateCategory(c *gin.Context) {
 func DeleteCategory(c *gin.Context) {
 	id := c.Param("id")
 
-	_, err := db.DB.Exec(
+	before, err := loadCategory(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	_, err = db.DB.Exec(
 		"UPDATE clothing_category SET clothes_cat_status = 2, updated_at = ? WHERE id = ?",
 		time.Now(), id,
 	)
@@ -117,5 +208,8 @@ func DeleteCategory(c *gin.Context) {
 		return
 	}
 
+	after, _ := loadCategory(id)
+	recordAudit(c, utils.AUDIT_ACTION_DELETE, utils.AUDIT_ENTITY_CATEGORY, int64(before.ID), before, after)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
 }
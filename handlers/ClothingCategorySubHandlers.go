@@ -3,9 +3,13 @@ package handlers
 import (
 	"clothingretail/db"
 	"clothingretail/models"
+	"clothingretail/utils"
 	"database/sql"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,27 +17,49 @@ import (
 
 // CreateCategorySub handles creating a new clothing subcategory
 func CreateCategorySub(c *gin.Context) {
-	var categorySub models.ClothingCategorySub
-
-	if err := c.ShouldBindJSON(&categorySub); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req models.ClothingCategorySubRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if !validateCategorySubRequest(c, &req, 0) {
+		return
+	}
+	pictures, ok := parseInlinePictures(c, &req)
+	if !ok {
 		return
 	}
 
+	categorySub := models.ClothingCategorySub{
+		IDClothingCategory:    req.IDClothingCategory,
+		ClothesCatNameSub:     req.ClothesCatNameSub,
+		ClothesCatLocationSub: req.ClothesCatLocationSub,
+		IDClothingLocation:    req.IDClothingLocation,
+	}
 	categorySub.CreatedAt = time.Now()
 	categorySub.UpdatedAt = time.Now()
 	categorySub.ClothesCatStatusSub = 1
 
+	// Pictures sent inline are stored once the subcategory has its id
+	categorySub.ClothesPicture1 = nil
+	categorySub.ClothesPicture2 = nil
+	categorySub.ClothesPicture3 = nil
+	categorySub.ClothesPicture4 = nil
+	categorySub.ClothesPicture5 = nil
+
+	// Attributes are set through /api/categories-sub/:id/attributes
+	categorySub.Attributes = map[string][]string{}
+
 	result, err := db.DB.Exec(
 		`INSERT INTO clothing_category_sub (id_clothing_category, clothes_cat_name_sub, clothes_cat_location_sub, 
-         clothes_picture_1, clothes_picture_2, clothes_picture_3, clothes_picture_4, clothes_picture_5, 
-         clothes_cat_status_sub, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+         id_clothing_location, clothes_cat_status_sub, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
 		categorySub.IDClothingCategory, categorySub.ClothesCatNameSub, categorySub.ClothesCatLocationSub,
-		categorySub.ClothesPicture1, categorySub.ClothesPicture2, categorySub.ClothesPicture3,
-		categorySub.ClothesPicture4, categorySub.ClothesPicture5, categorySub.ClothesCatStatusSub,
-		categorySub.CreatedAt, categorySub.UpdatedAt,
+		categorySub.IDClothingLocation, categorySub.ClothesCatStatusSub, categorySub.CreatedAt, categorySub.UpdatedAt,
 	)
 
+	if isUniqueViolation(err) {
+		respondFieldErrors(c, http.StatusConflict, fieldErrors{"clothes_cat_name_sub": "already exists in this category"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		fmt.Printf("Error inserting category sub: %v\n", err)
@@ -43,27 +69,141 @@ func CreateCategorySub(c *gin.Context) {
 	id, _ := result.LastInsertId()
 	categorySub.ID = int(id)
 
+	if len(pictures) > 0 {
+		if err := storeInlinePictures(categorySub.ID, pictures); err != nil {
+			log.Printf("Error storing pictures of category sub %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Subcategory created but its pictures could not be stored"})
+			return
+		}
+		if loaded, err := loadCategorySub(strconv.FormatInt(id, 10)); err == nil {
+			categorySub = *loaded
+		}
+	}
+
+	recordAudit(c, utils.AUDIT_ACTION_CREATE, utils.AUDIT_ENTITY_CATEGORY_SUB, id, nil, categorySub)
+
 	c.JSON(http.StatusCreated, categorySub)
 }
 
-// GetCategoriesSub retrieves all clothing subcategories
+// validateCategorySubRequest trims the request, checks the category is active and the name is not taken by
+// another active subcategory of that category, compared without case. The location is resolved to an active
+// location and both its id and name are set on the request. It reports the failing fields itself and returns
+// false when the request is refused.
+func validateCategorySubRequest(c *gin.Context, req *models.ClothingCategorySubRequest, excludeID int) bool {
+	req.ClothesCatNameSub = strings.TrimSpace(req.ClothesCatNameSub)
+	req.ClothesCatLocationSub = strings.TrimSpace(req.ClothesCatLocationSub)
+
+	var categoryStatus int
+	err := db.DB.QueryRow(
+		"SELECT clothes_cat_status FROM clothing_category WHERE id = ?",
+		req.IDClothingCategory,
+	).Scan(&categoryStatus)
+	if err != nil || categoryStatus != utils.CAT_STATUS_ACTIVE {
+		respondFieldErrors(c, http.StatusBadRequest, fieldErrors{"id_clothing_category": "is not an active category"})
+		return false
+	}
+
+	location, field, err := resolveLocation(db.DB, req.IDClothingLocation, req.ClothesCatLocationSub)
+	if err != nil {
+		log.Printf("Error resolving location: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate subcategory"})
+		return false
+	}
+	if location == nil {
+		respondFieldErrors(c, http.StatusBadRequest, fieldErrors{field: "is not an active location"})
+		return false
+	}
+	req.IDClothingLocation = location.ID
+	req.ClothesCatLocationSub = location.LocationName
+
+	var duplicates int
+	err = db.DB.QueryRow(
+		`SELECT COUNT(*) FROM clothing_category_sub WHERE id_clothing_category = ?
+         AND clothes_cat_name_sub = ? COLLATE NOCASE AND clothes_cat_status_sub = ? AND id != ?`,
+		req.IDClothingCategory, req.ClothesCatNameSub, utils.CAT_SUB_STATUS_ACTIVE, excludeID,
+	).Scan(&duplicates)
+	if err != nil {
+		log.Printf("Error checking subcategory name: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate subcategory"})
+		return false
+	}
+	if duplicates > 0 {
+		respondFieldErrors(c, http.StatusConflict, fieldErrors{"clothes_cat_name_sub": "already exists in this category"})
+		return false
+	}
+
+	return true
+}
+
+// categorySubListSpec lists the fields GetCategoriesSub sorts and filters on
+var categorySubListSpec = listSpec{
+	fields: map[string]listField{
+		"id":                       {"id", listFieldInt},
+		"id_clothing_category":     {"id_clothing_category", listFieldInt},
+		"clothes_cat_name_sub":     {"clothes_cat_name_sub", listFieldText},
+		"clothes_cat_location_sub": {"clothes_cat_location_sub", listFieldText},
+		"id_clothing_location":     {"id_clothing_location", listFieldInt},
+		"clothes_cat_status_sub":   {"clothes_cat_status_sub", listFieldInt},
+		"created_at":               {"created_at", listFieldTime},
+		"updated_at":               {"updated_at", listFieldTime},
+	},
+	defaultSort:   "clothes_cat_name_sub",
+	statusField:   "clothes_cat_status_sub",
+	statusDefault: utils.CAT_SUB_STATUS_ACTIVE,
+	statusNames:   utils.CatSubTransReverse,
+}
+
+// GetCategoriesSub retrieves the active clothing subcategories a page at a time, see listSpec for the parameters.
+// Attribute filters are given as attr[code]=value1,value2, with facets=true the response becomes
+// {"items": [...], "facets": [...]} carrying the counts per attribute value over every page.
 func GetCategoriesSub(c *gin.Context) {
 	categoryID := c.Query("category_id")
 
-	query := `SELECT id, id_clothing_category, clothes_cat_name_sub, clothes_cat_location_sub, 
-                  clothes_picture_1, clothes_picture_2, clothes_picture_3, clothes_picture_4, clothes_picture_5, 
-                  clothes_cat_status_sub, created_at, updated_at FROM clothing_category_sub WHERE clothes_cat_status_sub = 1`
+	list, err := parseListQuery(c, categorySubListSpec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	var rows *sql.Rows
-	var err error
+	attributes, err := loadActiveAttributes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	filters, err := parseAttributeFilters(c, attributes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
+	// The conditions shared by the list and the facets
+	conditions := ""
+	var conditionArgs []interface{}
 	if categoryID != "" {
-		query += " AND id_clothing_category = ?"
-		rows, err = db.DB.Query(query, categoryID)
-	} else {
-		rows, err = db.DB.Query(query)
+		conditions += " AND id_clothing_category = ?"
+		conditionArgs = append(conditionArgs, categoryID)
+	}
+	conditions, conditionArgs = list.filter(conditions, conditionArgs)
+
+	query := `SELECT id, id_clothing_category, clothes_cat_name_sub, clothes_cat_location_sub, 
+                  COALESCE(id_clothing_location, 0), clothes_picture_1, clothes_picture_2, clothes_picture_3, clothes_picture_4, clothes_picture_5, 
+                  clothes_cat_status_sub, created_at, updated_at FROM clothing_category_sub WHERE 1=1` + conditions
+
+	args := append([]interface{}{}, conditionArgs...)
+
+	clause, clauseArgs := attributeFilterClause(filters, "")
+	query += clause
+	args = append(args, clauseArgs...)
+
+	total, err := list.total(query, args)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
-	fmt.Printf("Query: %s\n", query)
+	list.setHeaders(c, total)
+	query, args = list.page(query, args)
+
+	rows, err := db.DB.Query(query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		fmt.Printf("Error scanning categories: %v\n", err)
@@ -72,108 +212,136 @@ func GetCategoriesSub(c *gin.Context) {
 	defer rows.Close()
 
 	categoriesSub := []models.ClothingCategorySub{}
+	var subIDs []int
 	for rows.Next() {
 		var categorySub models.ClothingCategorySub
 		// Use sql.NullString for nullable fields
-		var pic1, pic2, pic3, pic4, pic5 sql.NullString
+		var pics [pictureSlots]sql.NullString
 
 		if err := rows.Scan(&categorySub.ID, &categorySub.IDClothingCategory, &categorySub.ClothesCatNameSub,
-			&categorySub.ClothesCatLocationSub, &pic1, &pic2, &pic3, &pic4, &pic5,
+			&categorySub.ClothesCatLocationSub, &categorySub.IDClothingLocation, &pics[0], &pics[1], &pics[2], &pics[3], &pics[4],
 			&categorySub.ClothesCatStatusSub, &categorySub.CreatedAt, &categorySub.UpdatedAt); err != nil {
 			fmt.Printf("Error scanning category sub: %v\n", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		// Convert NullString to *string (pointer, nil if NULL)
-		if pic1.Valid {
-			categorySub.ClothesPicture1 = &pic1.String
-		}
-		if pic2.Valid {
-			categorySub.ClothesPicture2 = &pic2.String
-		}
-		if pic3.Valid {
-			categorySub.ClothesPicture3 = &pic3.String
-		}
-		if pic4.Valid {
-			categorySub.ClothesPicture4 = &pic4.String
-		}
-		if pic5.Valid {
-			categorySub.ClothesPicture5 = &pic5.String
-		}
+		// Lists only carry thumbnail URLs, the pictures themselves are served separately
+		setPictureURLs(&categorySub, pics, utils.PICTURE_VARIANT_THUMB)
 
 		categoriesSub = append(categoriesSub, categorySub)
+		subIDs = append(subIDs, categorySub.ID)
 	}
-	fmt.Printf("Categories Sub: %v\n", categoriesSub)
+	rows.Close()
+
+	subAttributes, err := loadSubAttributes(subIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range categoriesSub {
+		categoriesSub[i].Attributes = subAttributes[categoriesSub[i].ID]
+	}
+
+	if c.Query("facets") == "true" {
+		facets, err := attributeFacets(conditions, conditionArgs, filters, attributes)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": categoriesSub, "facets": facets})
+		return
+	}
+
 	c.JSON(http.StatusOK, categoriesSub)
 }
 
-// GetCategorySubByID retrieves a single subcategory by ID
-func GetCategorySubByID(c *gin.Context) {
-	id := c.Param("id")
+// loadCategorySub reads a single subcategory by ID
+func loadCategorySub(id string) (*models.ClothingCategorySub, error) {
 	var categorySub models.ClothingCategorySub
-	var pic1, pic2, pic3, pic4, pic5 sql.NullString
+	var pics [pictureSlots]sql.NullString
 
 	err := db.DB.QueryRow(
 		`SELECT id, id_clothing_category, clothes_cat_name_sub, clothes_cat_location_sub, 
-         clothes_picture_1, clothes_picture_2, clothes_picture_3, clothes_picture_4, clothes_picture_5, 
+         COALESCE(id_clothing_location, 0), clothes_picture_1, clothes_picture_2, clothes_picture_3, clothes_picture_4, clothes_picture_5, 
          clothes_cat_status_sub, created_at, updated_at FROM clothing_category_sub WHERE id = ?`,
 		id,
 	).Scan(&categorySub.ID, &categorySub.IDClothingCategory, &categorySub.ClothesCatNameSub,
-		&categorySub.ClothesCatLocationSub, &pic1, &pic2, &pic3, &pic4, &pic5,
+		&categorySub.ClothesCatLocationSub, &categorySub.IDClothingLocation, &pics[0], &pics[1], &pics[2], &pics[3], &pics[4],
 		&categorySub.ClothesCatStatusSub, &categorySub.CreatedAt, &categorySub.UpdatedAt)
 
+	if err != nil {
+		return nil, err
+	}
+	setPictureURLs(&categorySub, pics, utils.PICTURE_VARIANT_FULL)
+
+	subAttributes, err := loadSubAttributes([]int{categorySub.ID})
+	if err != nil {
+		return nil, err
+	}
+	categorySub.Attributes = subAttributes[categorySub.ID]
+	return &categorySub, nil
+}
+
+// GetCategorySubByID retrieves a single subcategory by ID
+func GetCategorySubByID(c *gin.Context) {
+	categorySub, err := loadCategorySub(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Subcategory not found"})
 		fmt.Printf("Error scanning category sub: %v\n", err)
 		return
 	}
-	// Convert NullString to *string (pointer, nil if NULL)
-	if pic1.Valid {
-		categorySub.ClothesPicture1 = &pic1.String
-	}
-	if pic2.Valid {
-		categorySub.ClothesPicture2 = &pic2.String
-	}
-	if pic3.Valid {
-		categorySub.ClothesPicture3 = &pic3.String
-	}
-	if pic4.Valid {
-		categorySub.ClothesPicture4 = &pic4.String
-	}
-	if pic5.Valid {
-		categorySub.ClothesPicture5 = &pic5.String
-	}
 	c.JSON(http.StatusOK, categorySub)
 }
 
 // UpdateCategorySub updates an existing subcategory
 func UpdateCategorySub(c *gin.Context) {
 	id := c.Param("id")
-	var categorySub models.ClothingCategorySub
+	var req models.ClothingCategorySubRequest
+	if !bindJSON(c, &req) {
+		return
+	}
 
-	if err := c.ShouldBindJSON(&categorySub); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	before, err := loadCategorySub(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subcategory not found"})
 		return
 	}
 
-	categorySub.UpdatedAt = time.Now()
+	if !validateCategorySubRequest(c, &req, before.ID) {
+		return
+	}
+	pictures, ok := parseInlinePictures(c, &req)
+	if !ok {
+		return
+	}
 
-	_, err := db.DB.Exec(
+	// Pictures are stored through their own slots, only those sent inline are replaced
+	_, err = db.DB.Exec(
 		`UPDATE clothing_category_sub SET id_clothing_category = ?, clothes_cat_name_sub = ?, 
-         clothes_cat_location_sub = ?, clothes_picture_1 = ?, clothes_picture_2 = ?, clothes_picture_3 = ?, 
-         clothes_picture_4 = ?, clothes_picture_5 = ?, updated_at = ? WHERE id = ?`,
-		categorySub.IDClothingCategory, categorySub.ClothesCatNameSub, categorySub.ClothesCatLocationSub,
-		categorySub.ClothesPicture1, categorySub.ClothesPicture2, categorySub.ClothesPicture3,
-		categorySub.ClothesPicture4, categorySub.ClothesPicture5, categorySub.UpdatedAt, id,
+         clothes_cat_location_sub = ?, id_clothing_location = ?, updated_at = ? WHERE id = ?`,
+		req.IDClothingCategory, req.ClothesCatNameSub, req.ClothesCatLocationSub, req.IDClothingLocation, time.Now(), id,
 	)
 
+	if isUniqueViolation(err) {
+		respondFieldErrors(c, http.StatusConflict, fieldErrors{"clothes_cat_name_sub": "already exists in this category"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		fmt.Printf("Error updating category sub: %v\n", err)
 		return
 	}
 
+	if err := storeInlinePictures(before.ID, pictures); err != nil {
+		log.Printf("Error storing pictures of category sub %d: %v", before.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Subcategory updated but its pictures could not be stored"})
+		return
+	}
+
+	after, _ := loadCategorySub(id)
+	recordAudit(c, utils.AUDIT_ACTION_UPDATE, utils.AUDIT_ENTITY_CATEGORY_SUB, int64(before.ID), before, after)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Subcategory updated successfully"})
 }
 
@@ -181,7 +349,13 @@ func UpdateCategorySub(c *gin.Context) {
 func DeleteCategorySub(c *gin.Context) {
 	id := c.Param("id")
 
-	_, err := db.DB.Exec(
+	before, err := loadCategorySub(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subcategory not found"})
+		return
+	}
+
+	_, err = db.DB.Exec(
 		"UPDATE clothing_category_sub SET clothes_cat_status_sub = 2, updated_at = ? WHERE id = ?",
 		time.Now(), id,
 	)
@@ -192,5 +366,8 @@ func DeleteCategorySub(c *gin.Context) {
 		return
 	}
 
+	after, _ := loadCategorySub(id)
+	recordAudit(c, utils.AUDIT_ACTION_DELETE, utils.AUDIT_ENTITY_CATEGORY_SUB, int64(before.ID), before, after)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Subcategory deleted successfully"})
 }
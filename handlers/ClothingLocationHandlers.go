@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"clothingretail/db"
+	"clothingretail/models"
+	"clothingretail/utils"
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// locationColumns are the columns scanLocation reads, in its order
+const locationColumns = `id, location_name, location_type, location_address, location_status, created_at, updated_at`
+
+func scanLocation(row interface{ Scan(...interface{}) error }) (*models.ClothingLocation, error) {
+	var location models.ClothingLocation
+	err := row.Scan(&location.ID, &location.LocationName, &location.LocationType, &location.LocationAddress,
+		&location.LocationStatus, &location.CreatedAt, &location.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+// loadLocation reads a single location by ID
+func loadLocation(q rowQuerier, id interface{}) (*models.ClothingLocation, error) {
+	return scanLocation(q.QueryRow("SELECT "+locationColumns+" FROM clothing_location WHERE id = ?", id))
+}
+
+// activeLocation reads an active location by ID, nil when there is no such location
+func activeLocation(q rowQuerier, id int) (*models.ClothingLocation, error) {
+	location, err := scanLocation(q.QueryRow(
+		"SELECT "+locationColumns+" FROM clothing_location WHERE id = ? AND location_status = ?",
+		id, utils.LOCATION_STATUS_ACTIVE,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return location, err
+}
+
+// resolveLocation finds the active location a request names, by id when one is given and otherwise by name
+// without case. When there is none it returns nil and the request field to report.
+func resolveLocation(q rowQuerier, id int, name string) (*models.ClothingLocation, string, error) {
+	if id != 0 {
+		location, err := activeLocation(q, id)
+		return location, "id_clothing_location", err
+	}
+	if name == "" {
+		return nil, "id_clothing_location", nil
+	}
+
+	location, err := scanLocation(q.QueryRow(
+		"SELECT "+locationColumns+" FROM clothing_location WHERE location_name = ? COLLATE NOCASE AND location_status = ?",
+		name, utils.LOCATION_STATUS_ACTIVE,
+	))
+	if err == sql.ErrNoRows {
+		return nil, "clothes_cat_location_sub", nil
+	}
+	return location, "clothes_cat_location_sub", err
+}
+
+// locationStock is the quantity of a size available at a location, the balance of its active inventory movements.
+// Stock reserved for rentals that begin later is already booked out, see GetInventory.
+func locationStock(q rowQuerier, sizeID, locationID int) (int, error) {
+	var available int
+	err := q.QueryRow(
+		`SELECT COALESCE(SUM(clothes_qty_in - clothes_qty_out), 0) FROM clothing_inventory_movement
+         WHERE id_clothing_size = ? AND id_clothing_location = ? AND clothes_cat_status_sub = ?`,
+		sizeID, locationID, utils.CLOTHES_MOV_STATUS_ACTIVE,
+	).Scan(&available)
+	return available, err
+}
+
+// locationInUse tells why a location cannot be deactivated, an empty string when it is free to go
+func locationInUse(q rowQuerier, locationID int) (string, error) {
+	var subcategories int
+	err := q.QueryRow(
+		"SELECT COUNT(*) FROM clothing_category_sub WHERE id_clothing_location = ? AND clothes_cat_status_sub = ?",
+		locationID, utils.CAT_SUB_STATUS_ACTIVE,
+	).Scan(&subcategories)
+	if err != nil {
+		return "", err
+	}
+	if subcategories > 0 {
+		return "Location is still assigned to active subcategories", nil
+	}
+
+	var onHand int
+	err = q.QueryRow(
+		`SELECT COUNT(*) FROM (SELECT id_clothing_size FROM clothing_inventory_movement WHERE id_clothing_location = ?
+         AND clothes_cat_status_sub = ? GROUP BY id_clothing_size HAVING SUM(clothes_qty_in - clothes_qty_out) != 0)`,
+		locationID, utils.CLOTHES_MOV_STATUS_ACTIVE,
+	).Scan(&onHand)
+	if err != nil {
+		return "", err
+	}
+	if onHand > 0 {
+		return "Location still has stock on hand", nil
+	}
+
+	var rentedOut int
+	err = q.QueryRow(
+		"SELECT COUNT(*) FROM clothing_rental WHERE id_clothing_location = ? AND clothes_rent_status = ?",
+		locationID, utils.CLOTHES_RENT_STATUS_RENTED,
+	).Scan(&rentedOut)
+	if err != nil {
+		return "", err
+	}
+	if rentedOut > 0 {
+		return "Location has rentals that are not returned yet", nil
+	}
+	return "", nil
+}
+
+// validateLocationRequest trims the request, defaults the type to a store and checks the name is not taken
+// by another location, compared without case. It reports the failing fields itself and returns false when
+// the request is refused.
+func validateLocationRequest(c *gin.Context, req *models.LocationRequest, excludeID int) bool {
+	req.LocationName = strings.TrimSpace(req.LocationName)
+	req.LocationAddress = strings.TrimSpace(req.LocationAddress)
+	if req.LocationType == 0 {
+		req.LocationType = utils.LOCATION_TYPE_STORE
+	}
+
+	// Names are unique in the schema, inactive locations included, so they can be renamed back safely
+	var duplicates int
+	err := db.DB.QueryRow(
+		"SELECT COUNT(*) FROM clothing_location WHERE location_name = ? COLLATE NOCASE AND id != ?",
+		req.LocationName, excludeID,
+	).Scan(&duplicates)
+	if err != nil {
+		log.Printf("Error checking location name: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate location"})
+		return false
+	}
+	if duplicates > 0 {
+		respondFieldErrors(c, http.StatusConflict, fieldErrors{"location_name": "already exists"})
+		return false
+	}
+
+	return true
+}
+
+// CreateLocation handles creating a new store or warehouse
+func CreateLocation(c *gin.Context) {
+	var req models.LocationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if !validateLocationRequest(c, &req, 0) {
+		return
+	}
+
+	location := models.ClothingLocation{
+		LocationName:    req.LocationName,
+		LocationType:    req.LocationType,
+		LocationAddress: req.LocationAddress,
+		LocationStatus:  utils.LOCATION_STATUS_ACTIVE,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	result, err := db.DB.Exec(
+		`INSERT INTO clothing_location (location_name, location_type, location_address, location_status, created_at, updated_at)
+         VALUES (?, ?, ?, ?, ?, ?)`,
+		location.LocationName, location.LocationType, location.LocationAddress, location.LocationStatus,
+		location.CreatedAt, location.UpdatedAt,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	location.ID = int(id)
+
+	recordAudit(c, utils.AUDIT_ACTION_CREATE, utils.AUDIT_ENTITY_LOCATION, id, nil, location)
+
+	c.JSON(http.StatusCreated, location)
+}
+
+// locationListSpec lists the fields GetLocations sorts and filters on
+var locationListSpec = listSpec{
+	fields: map[string]listField{
+		"id":              {"id", listFieldInt},
+		"location_name":   {"location_name", listFieldText},
+		"location_type":   {"location_type", listFieldInt},
+		"location_status": {"location_status", listFieldInt},
+		"created_at":      {"created_at", listFieldTime},
+		"updated_at":      {"updated_at", listFieldTime},
+	},
+	defaultSort:   "location_name",
+	statusField:   "location_status",
+	statusDefault: utils.LOCATION_STATUS_ACTIVE,
+	statusNames:   utils.LocationStatusTransReverse,
+}
+
+// GetLocations retrieves the active locations a page at a time, see listSpec for the parameters
+func GetLocations(c *gin.Context) {
+	list, err := parseListQuery(c, locationListSpec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, args, err := list.listAll(c, "SELECT "+locationColumns+" FROM clothing_location WHERE 1=1", nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	locations := []models.ClothingLocation{}
+	for rows.Next() {
+		location, err := scanLocation(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		locations = append(locations, *location)
+	}
+
+	c.JSON(http.StatusOK, locations)
+}
+
+// GetLocationByID retrieves a single location by ID
+func GetLocationByID(c *gin.Context) {
+	location, err := loadLocation(db.DB, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Location not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, location)
+}
+
+// UpdateLocation updates an existing location. A new name is carried over to the subcategories kept there.
+func UpdateLocation(c *gin.Context) {
+	id := c.Param("id")
+	var req models.LocationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	before, err := loadLocation(db.DB, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Location not found"})
+		return
+	}
+
+	if !validateLocationRequest(c, &req, before.ID) {
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.Exec(
+		"UPDATE clothing_location SET location_name = ?, location_type = ?, location_address = ?, updated_at = ? WHERE id = ?",
+		req.LocationName, req.LocationType, req.LocationAddress, now, before.ID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.LocationName != before.LocationName {
+		_, err = tx.Exec(
+			"UPDATE clothing_category_sub SET clothes_cat_location_sub = ?, updated_at = ? WHERE id_clothing_location = ?",
+			req.LocationName, now, before.ID,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	after, _ := loadLocation(db.DB, id)
+	recordAudit(c, utils.AUDIT_ACTION_UPDATE, utils.AUDIT_ENTITY_LOCATION, int64(before.ID), before, after)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Location updated successfully"})
+}
+
+// DeleteLocation soft deletes a location that no subcategory, stock or open rental refers to anymore
+func DeleteLocation(c *gin.Context) {
+	id := c.Param("id")
+
+	before, err := loadLocation(db.DB, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Location not found"})
+		return
+	}
+
+	reason, err := locationInUse(db.DB, before.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if reason != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": reason})
+		return
+	}
+
+	_, err = db.DB.Exec(
+		"UPDATE clothing_location SET location_status = ?, updated_at = ? WHERE id = ?",
+		utils.LOCATION_STATUS_INACTIVE, time.Now(), before.ID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	after, _ := loadLocation(db.DB, id)
+	recordAudit(c, utils.AUDIT_ACTION_DELETE, utils.AUDIT_ENTITY_LOCATION, int64(before.ID), before, after)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Location deleted successfully"})
+}
+
+// TransferStock moves stock of a size between two active locations. It books a TRANSFER movement out of the
+// source and one into the destination, the source needs the quantity on hand.
+func TransferStock(c *gin.Context) {
+	var req models.StockTransferRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.FromLocation == req.ToLocation {
+		respondFieldErrors(c, http.StatusBadRequest, fieldErrors{"to_location": "must differ from from_location"})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var subID int
+	err = tx.QueryRow(
+		"SELECT id_clothing_category_sub FROM clothing_size WHERE id = ? AND clothes_size_status = ?",
+		req.IDClothingSize, utils.CLOTHES_SIZE_STATUS_ACTIVE,
+	).Scan(&subID)
+	if err != nil {
+		respondFieldErrors(c, http.StatusBadRequest, fieldErrors{"id_clothing_size": "is not an active size"})
+		return
+	}
+
+	fields := fieldErrors{}
+	for field, locationID := range map[string]int{"from_location": req.FromLocation, "to_location": req.ToLocation} {
+		location, err := activeLocation(tx, locationID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if location == nil {
+			fields[field] = "is not an active location"
+		}
+	}
+	if len(fields) > 0 {
+		respondFieldErrors(c, http.StatusBadRequest, fields)
+		return
+	}
+
+	fromStock, err := locationStock(tx, req.IDClothingSize, req.FromLocation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if fromStock < req.ClothesQty {
+		respondFieldErrors(c, http.StatusConflict, fieldErrors{"clothes_qty": "exceeds the stock available at from_location"})
+		return
+	}
+	toStock, err := locationStock(tx, req.IDClothingSize, req.ToLocation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	movements := []struct {
+		location, in, out, total int
+	}{
+		{req.FromLocation, 0, req.ClothesQty, fromStock - req.ClothesQty},
+		{req.ToLocation, req.ClothesQty, 0, toStock + req.ClothesQty},
+	}
+	for _, movement := range movements {
+		_, err = tx.Exec(
+			`INSERT INTO clothing_inventory_movement (id_clothing_category, id_clothing_size, id_clothing_location,
+             clothes_movement_action, clothes_qty_in, clothes_qty_out, clothes_qty_total,
+             clothes_cat_status_sub, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			subID, req.IDClothingSize, movement.location, utils.CLOTHES_MOV_ACTION_TRANSFER,
+			movement.in, movement.out, movement.total, utils.CLOTHES_MOV_STATUS_ACTIVE, now, now,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, utils.AUDIT_ACTION_UPDATE, utils.AUDIT_ENTITY_SIZE, int64(req.IDClothingSize),
+		gin.H{"from_location_stock": fromStock, "to_location_stock": toStock},
+		gin.H{
+			"from_location":         req.FromLocation,
+			"to_location":           req.ToLocation,
+			"from_location_stock":   fromStock - req.ClothesQty,
+			"to_location_stock":     toStock + req.ClothesQty,
+			"clothes_transfer_note": strings.TrimSpace(req.ClothesTransferNote),
+		},
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "Stock transferred successfully",
+		"from_location_stock": fromStock - req.ClothesQty,
+		"to_location_stock":   toStock + req.ClothesQty,
+	})
+}
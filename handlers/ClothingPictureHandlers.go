@@ -0,0 +1,548 @@
+package handlers
+
+import (
+	"clothingretail/db"
+	"clothingretail/models"
+	"clothingretail/storage"
+	"clothingretail/utils"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// picturesKeyPrefix marks a clothes_picture_N value as a reference to a stored file,
+	// anything else in those columns is a legacy inline base64 picture
+	picturesKeyPrefix = "pictures/"
+
+	// pictureSlots is the number of pictures a subcategory can have
+	pictureSlots = 5
+
+	// maxPictureSize is the largest picture accepted for upload
+	maxPictureSize = 5 << 20
+
+	// pictureFormField is the multipart field holding the uploaded picture
+	pictureFormField = "picture"
+)
+
+// pictureColumn returns the clothing_category_sub column that holds a picture slot
+func pictureColumn(slot int) string {
+	return fmt.Sprintf("clothes_picture_%d", slot)
+}
+
+// parsePictureSlot validates the :slot parameter
+func parsePictureSlot(c *gin.Context) (int, bool) {
+	slot, err := strconv.Atoi(c.Param("slot"))
+	if err != nil || slot < 1 || slot > pictureSlots {
+		return 0, false
+	}
+	return slot, true
+}
+
+// isPictureKey reports whether a picture column value references a stored file
+func isPictureKey(value string) bool {
+	return strings.HasPrefix(value, picturesKeyPrefix)
+}
+
+// pictureVersion is the unique part of a picture key, it changes whenever the picture is replaced
+func pictureVersion(key string) string {
+	return strings.TrimSuffix(path.Base(key), path.Ext(key))
+}
+
+// pictureURL is where the API serves a stored picture, versioned so it can be cached for good
+func pictureURL(subID, slot int, key string) string {
+	return fmt.Sprintf("/api/categories-sub/%d/pictures/%d?v=%s", subID, slot, url.QueryEscape(pictureVersion(key)))
+}
+
+// pictureVariant is one stored size of a picture
+type pictureVariant struct {
+	key    string
+	width  int
+	height int
+}
+
+// setPictureURLs fills the picture fields of a subcategory with the URLs of one variant of its stored pictures
+func setPictureURLs(categorySub *models.ClothingCategorySub, pics [pictureSlots]sql.NullString, variant string) {
+	fields := []**string{
+		&categorySub.ClothesPicture1, &categorySub.ClothesPicture2, &categorySub.ClothesPicture3,
+		&categorySub.ClothesPicture4, &categorySub.ClothesPicture5,
+	}
+	for i, pic := range pics {
+		if pic.Valid && isPictureKey(pic.String) {
+			link := pictureURL(categorySub.ID, i+1, pic.String)
+			if variant != utils.PICTURE_VARIANT_FULL {
+				link += "&size=" + variant
+			}
+			*fields[i] = &link
+		}
+	}
+}
+
+// savePicture sanitises an uploaded picture and stores every variant of it, returning their keys
+func savePicture(subID, slot int, data []byte) (map[string]pictureVariant, error) {
+	processed, err := utils.ProcessPicture(data)
+	if err != nil {
+		return nil, err
+	}
+	return storePicture(subID, slot, processed)
+}
+
+// storePicture stores every variant of a sanitised picture, returning their keys
+func storePicture(subID, slot int, processed map[string]utils.ProcessedPicture) (map[string]pictureVariant, error) {
+	name, err := utils.GenerateToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	variants := map[string]pictureVariant{}
+	for _, variant := range utils.PictureVariants() {
+		suffix := ""
+		if variant != utils.PICTURE_VARIANT_FULL {
+			suffix = "-" + variant
+		}
+
+		picture := processed[variant]
+		key, err := storage.Store.Put(
+			fmt.Sprintf("%s%d/%d-%s%s%s", picturesKeyPrefix, subID, slot, name, suffix, utils.PICTURE_EXTENSION),
+			picture.Data, utils.PICTURE_CONTENT_TYPE,
+		)
+		if err != nil {
+			for _, stored := range variants {
+				removePicture(stored.key)
+			}
+			return nil, err
+		}
+		variants[variant] = pictureVariant{key: key, width: picture.Width, height: picture.Height}
+	}
+
+	return variants, nil
+}
+
+// removePicture deletes a stored picture, legacy inline values have nothing to delete
+func removePicture(key string) {
+	if !isPictureKey(key) {
+		return
+	}
+	if err := storage.Store.Delete(key); err != nil {
+		log.Printf("Error removing picture %s: %v", key, err)
+	}
+}
+
+// loadPictureKey returns the raw value stored in a picture slot of a subcategory
+func loadPictureKey(subID, slot int) (string, error) {
+	var value sql.NullString
+	err := db.DB.QueryRow(
+		"SELECT "+pictureColumn(slot)+" FROM clothing_category_sub WHERE id = ?", subID,
+	).Scan(&value)
+	return value.String, err
+}
+
+// loadPictureVariantKey returns the key of a resized copy, falling back to the full picture
+// for pictures stored before copies were made
+func loadPictureVariantKey(subID, slot int, variant string) (string, error) {
+	if variant != utils.PICTURE_VARIANT_FULL {
+		var key string
+		err := db.DB.QueryRow(
+			`SELECT picture_storage_key FROM clothing_picture_variants
+             WHERE id_clothing_category_sub = ? AND picture_slot = ? AND picture_variant = ?`,
+			subID, slot, variant,
+		).Scan(&key)
+		if err == nil {
+			return key, nil
+		}
+		if err != sql.ErrNoRows {
+			return "", err
+		}
+	}
+	return loadPictureKey(subID, slot)
+}
+
+// replacePicture points a picture slot at newly stored variants, or clears it when variants is nil,
+// and deletes the files of the picture it replaces
+func replacePicture(subID, slot int, variants map[string]pictureVariant) error {
+	var previous []string
+	if key, err := loadPictureKey(subID, slot); err == nil && key != "" {
+		previous = append(previous, key)
+	}
+
+	rows, err := db.DB.Query(
+		"SELECT picture_storage_key FROM clothing_picture_variants WHERE id_clothing_category_sub = ? AND picture_slot = ?",
+		subID, slot,
+	)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return err
+		}
+		previous = append(previous, key)
+	}
+	rows.Close()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var fullKey interface{}
+	if variants != nil {
+		fullKey = variants[utils.PICTURE_VARIANT_FULL].key
+	}
+
+	now := time.Now()
+	_, err = tx.Exec("UPDATE clothing_category_sub SET "+pictureColumn(slot)+" = ?, updated_at = ? WHERE id = ?", fullKey, now, subID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM clothing_picture_variants WHERE id_clothing_category_sub = ? AND picture_slot = ?", subID, slot)
+	if err != nil {
+		return err
+	}
+
+	for name, variant := range variants {
+		if name == utils.PICTURE_VARIANT_FULL {
+			continue
+		}
+		_, err = tx.Exec(
+			`INSERT INTO clothing_picture_variants (id_clothing_category_sub, picture_slot, picture_variant,
+             picture_storage_key, picture_width, picture_height, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			subID, slot, name, variant.key, variant.width, variant.height, now,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, key := range previous {
+		removePicture(key)
+	}
+	return nil
+}
+
+// UploadCategorySubPicture stores a picture for one slot of a subcategory, replacing the previous one.
+// The upload is checked by its content, re-encoded without metadata and stored in every variant size.
+func UploadCategorySubPicture(c *gin.Context) {
+	subID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subcategory ID"})
+		return
+	}
+	slot, ok := parsePictureSlot(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Picture slot must be between 1 and %d", pictureSlots)})
+		return
+	}
+
+	before, err := loadCategorySub(strconv.Itoa(subID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subcategory not found"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPictureSize+(1<<20))
+	fileHeader, err := c.FormFile(pictureFormField)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Picture file is required in the \"picture\" field"})
+		return
+	}
+	if fileHeader.Size > maxPictureSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image size must be less than 5MB"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxPictureSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variants, err := savePicture(subID, slot, data)
+	if err != nil {
+		if err == utils.ErrUnsupportedImage {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := replacePicture(subID, slot, variants); err != nil {
+		for _, variant := range variants {
+			removePicture(variant.key)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	after, _ := loadCategorySub(strconv.Itoa(subID))
+	recordAudit(c, utils.AUDIT_ACTION_UPDATE, utils.AUDIT_ENTITY_CATEGORY_SUB, int64(subID), before, after)
+
+	fullKey := variants[utils.PICTURE_VARIANT_FULL].key
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Picture uploaded successfully",
+		"slot":      slot,
+		"url":       pictureURL(subID, slot, fullKey),
+		"thumb_url": pictureURL(subID, slot, fullKey) + "&size=" + utils.PICTURE_VARIANT_THUMB,
+	})
+}
+
+// GetCategorySubPicture serves a stored picture in the size asked for with ?size=full|preview|thumb.
+// Versioned URLs never change content, so they are cached for good.
+func GetCategorySubPicture(c *gin.Context) {
+	subID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subcategory ID"})
+		return
+	}
+	slot, ok := parsePictureSlot(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Picture slot must be between 1 and %d", pictureSlots)})
+		return
+	}
+
+	variant := c.DefaultQuery("size", utils.PICTURE_VARIANT_FULL)
+	if variant != utils.PICTURE_VARIANT_FULL && variant != utils.PICTURE_VARIANT_PREVIEW && variant != utils.PICTURE_VARIANT_THUMB {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Size must be full, preview or thumb"})
+		return
+	}
+
+	fullKey, err := loadPictureKey(subID, slot)
+	if err != nil || fullKey == "" || !isPictureKey(fullKey) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Picture not found"})
+		return
+	}
+	key, err := loadPictureVariantKey(subID, slot, variant)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	version := pictureVersion(fullKey)
+	etag := `"` + version + "-" + variant + `"`
+	if c.Query("v") == version {
+		c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "private, no-cache")
+	}
+	c.Header("ETag", etag)
+
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	object, err := storage.Store.Get(key)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Picture not found"})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	defer object.Body.Close()
+
+	c.Header("Last-Modified", object.ModTime.UTC().Format(http.TimeFormat))
+	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, object.Body, nil)
+}
+
+// DeleteCategorySubPicture removes the picture of one slot together with its resized copies
+func DeleteCategorySubPicture(c *gin.Context) {
+	subID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subcategory ID"})
+		return
+	}
+	slot, ok := parsePictureSlot(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Picture slot must be between 1 and %d", pictureSlots)})
+		return
+	}
+
+	before, err := loadCategorySub(strconv.Itoa(subID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subcategory not found"})
+		return
+	}
+
+	key, _ := loadPictureKey(subID, slot)
+	if key == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Picture not found"})
+		return
+	}
+
+	if err := replacePicture(subID, slot, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	after, _ := loadCategorySub(strconv.Itoa(subID))
+	recordAudit(c, utils.AUDIT_ACTION_UPDATE, utils.AUDIT_ENTITY_CATEGORY_SUB, int64(subID), before, after)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Picture deleted successfully"})
+}
+
+// decodeInlinePicture decodes a legacy base64 picture, with or without its data URL prefix
+func decodeInlinePicture(value string) ([]byte, error) {
+	if strings.HasPrefix(value, "data:") {
+		if i := strings.Index(value, ","); i >= 0 {
+			value = value[i+1:]
+		}
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+}
+
+// parseInlinePictures decodes and sanitises the base64 pictures a subcategory request sends inline, by slot.
+// Empty values and the picture URLs the API returns leave their slot alone. Every slot that does not hold
+// a valid picture is reported with its field, nothing is stored and false is returned then.
+func parseInlinePictures(c *gin.Context, req *models.ClothingCategorySubRequest) (map[int]map[string]utils.ProcessedPicture, bool) {
+	values := []*string{req.ClothesPicture1, req.ClothesPicture2, req.ClothesPicture3, req.ClothesPicture4, req.ClothesPicture5}
+
+	pictures := map[int]map[string]utils.ProcessedPicture{}
+	fields := fieldErrors{}
+	for i, value := range values {
+		slot := i + 1
+		if value == nil || *value == "" || strings.HasPrefix(*value, "/api/") {
+			continue
+		}
+
+		data, err := decodeInlinePicture(*value)
+		if err != nil {
+			fields[pictureColumn(slot)] = "is not valid base64"
+			continue
+		}
+		if len(data) > maxPictureSize {
+			fields[pictureColumn(slot)] = "must be less than 5MB"
+			continue
+		}
+
+		processed, err := utils.ProcessPicture(data)
+		if err == utils.ErrUnsupportedImage {
+			fields[pictureColumn(slot)] = "must be a JPEG, PNG or WebP image"
+			continue
+		}
+		if err != nil {
+			fields[pictureColumn(slot)] = err.Error()
+			continue
+		}
+		pictures[slot] = processed
+	}
+
+	if len(fields) > 0 {
+		respondFieldErrors(c, http.StatusBadRequest, fields)
+		return nil, false
+	}
+	return pictures, true
+}
+
+// storeInlinePictures stores the pictures parseInlinePictures returned in the slots of a subcategory
+func storeInlinePictures(subID int, pictures map[int]map[string]utils.ProcessedPicture) error {
+	for slot, processed := range pictures {
+		variants, err := storePicture(subID, slot, processed)
+		if err != nil {
+			return err
+		}
+		if err := replacePicture(subID, slot, variants); err != nil {
+			for _, variant := range variants {
+				removePicture(variant.key)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateInlinePictures moves the base64 pictures still stored in clothing_category_sub into the storage backend
+func MigrateInlinePictures() error {
+	type inlinePicture struct {
+		subID int
+		slot  int
+		value string
+	}
+
+	rows, err := db.DB.Query(
+		`SELECT id, clothes_picture_1, clothes_picture_2, clothes_picture_3, clothes_picture_4, clothes_picture_5
+         FROM clothing_category_sub`,
+	)
+	if err != nil {
+		return err
+	}
+
+	var pending []inlinePicture
+	for rows.Next() {
+		var id int
+		var pics [pictureSlots]sql.NullString
+		if err := rows.Scan(&id, &pics[0], &pics[1], &pics[2], &pics[3], &pics[4]); err != nil {
+			rows.Close()
+			return err
+		}
+		for i, pic := range pics {
+			if pic.Valid && pic.String != "" && !isPictureKey(pic.String) {
+				pending = append(pending, inlinePicture{subID: id, slot: i + 1, value: pic.String})
+			}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	migrated := 0
+	for _, pic := range pending {
+		data, err := decodeInlinePicture(pic.value)
+		if err != nil {
+			log.Printf("Skipping picture %d of subcategory %d, it is not valid base64: %v", pic.slot, pic.subID, err)
+			continue
+		}
+
+		variants, err := savePicture(pic.subID, pic.slot, data)
+		if err != nil {
+			log.Printf("Skipping picture %d of subcategory %d: %v", pic.slot, pic.subID, err)
+			continue
+		}
+
+		if err := replacePicture(pic.subID, pic.slot, variants); err != nil {
+			for _, variant := range variants {
+				removePicture(variant.key)
+			}
+			return err
+		}
+		migrated++
+	}
+
+	if migrated > 0 {
+		log.Printf("Moved %d inline pictures to the storage backend", migrated)
+
+		// Give the space taken by the base64 blobs back to the file system
+		if _, err := db.DB.Exec("VACUUM"); err != nil {
+			log.Printf("Error vacuuming database: %v", err)
+		}
+	}
+
+	return nil
+}
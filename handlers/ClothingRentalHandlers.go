@@ -7,68 +7,12 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GetSizes retrieves all sizes, optionally filtered by subcategory
-func GetSizes(c *gin.Context) {
-	subcategoryID := c.Query("subcategory_id")
-
-	query := `SELECT id, id_clothing_category_sub, clothes_size_name, clothes_size_notes, 
-              clothes_size_status, created_at, updated_at 
-              FROM clothing_size WHERE clothes_size_status = 1`
-
-	var args []interface{}
-
-	if subcategoryID != "" {
-		query += " AND id_clothing_category_sub = ?"
-		args = append(args, subcategoryID)
-	}
-
-	query += " ORDER BY clothes_size_name"
-
-	rows, err := db.DB.Query(query, args...)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	defer rows.Close()
-
-	// Initialize with empty slice instead of nil
-	sizes := []struct {
-		ID                    int       `json:"id"`
-		IDClothingCategorySub int       `json:"id_clothing_category_sub"`
-		ClothesSizeName       string    `json:"clothes_size_name"`
-		ClothesSizeNotes      string    `json:"clothes_size_notes"`
-		ClothesSizeStatus     int       `json:"clothes_size_status"`
-		CreatedAt             time.Time `json:"created_at"`
-		UpdatedAt             time.Time `json:"updated_at"`
-	}{}
-
-	for rows.Next() {
-		var size struct {
-			ID                    int       `json:"id"`
-			IDClothingCategorySub int       `json:"id_clothing_category_sub"`
-			ClothesSizeName       string    `json:"clothes_size_name"`
-			ClothesSizeNotes      string    `json:"clothes_size_notes"`
-			ClothesSizeStatus     int       `json:"clothes_size_status"`
-			CreatedAt             time.Time `json:"created_at"`
-			UpdatedAt             time.Time `json:"updated_at"`
-		}
-		if err := rows.Scan(&size.ID, &size.IDClothingCategorySub, &size.ClothesSizeName,
-			&size.ClothesSizeNotes, &size.ClothesSizeStatus, &size.CreatedAt,
-			&size.UpdatedAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		sizes = append(sizes, size)
-	}
-
-	c.JSON(http.StatusOK, sizes)
-}
-
 // Helper function to parse multiple date/datetime formats
 func parseDateTime(dateStr string) (time.Time, error) {
 	// Try different formats
@@ -89,6 +33,19 @@ func parseDateTime(dateStr string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
 }
 
+// parseLocalDateTime parses like parseDateTime, but reads values without a time zone as local time.
+// Timestamps are stored in local time, so filters have to be compared in local time as well.
+func parseLocalDateTime(dateStr string) (time.Time, error) {
+	t, err := parseDateTime(dateStr)
+	if err != nil {
+		return t, err
+	}
+	if t.Location() == time.UTC && !strings.HasSuffix(dateStr, "Z") {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local), nil
+	}
+	return t.In(time.Local), nil
+}
+
 // RentClothing handles renting clothing items
 func RentClothing(c *gin.Context) {
 	var req models.RentalRequest
@@ -111,17 +68,69 @@ func RentClothing(c *gin.Context) {
 		return
 	}
 
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	// The size has to be one of the subcategory, otherwise the rental and its movement would disagree on what left
+	var sizeCount int
+	err = tx.QueryRow(
+		"SELECT COUNT(*) FROM clothing_size WHERE id = ? AND id_clothing_category_sub = ?",
+		req.IDClothingSize, req.IDClothingCategorySub,
+	).Scan(&sizeCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if sizeCount == 0 {
+		respondFieldErrors(c, http.StatusBadRequest, fieldErrors{"id_clothing_size": "does not belong to the subcategory"})
+		return
+	}
+
+	// The stock leaves the location the subcategory is kept at unless the request names another one
+	locationID := req.IDClothingLocation
+	if locationID == 0 {
+		err = tx.QueryRow(
+			"SELECT COALESCE(id_clothing_location, 0) FROM clothing_category_sub WHERE id = ?",
+			req.IDClothingCategorySub,
+		).Scan(&locationID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Subcategory not found"})
+			return
+		}
+	}
+	location, err := activeLocation(tx, locationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if location == nil {
+		respondFieldErrors(c, http.StatusBadRequest, fieldErrors{"id_clothing_location": "is not an active location"})
+		return
+	}
+
+	// The stock is only reported, not enforced: there is no way yet to book incoming stock, so a rental may
+	// take the balance of a size below zero. The movement keeps the running total either way.
+	available, err := locationStock(tx, req.IDClothingSize, location.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	now := time.Now()
 	rentalID := utils.GenerateID()
 
 	// Insert rental record - Fixed: use clothes_rent_status instead of clothes_cat_status_sub
-	_, err = db.DB.Exec(
+	_, err = tx.Exec(
 		`INSERT INTO clothing_rental (id, id_clothing_category_sub, id_clothing_size, id_clothing_customer, 
-         clothes_qty_rent, clothes_qty_return, 
+         id_clothing_location, clothes_qty_rent, clothes_qty_return, 
 		 clothes_rent_date_begin, clothes_rent_date_end, clothes_rent_date_actual_pickup, clothes_rent_date_actual_return, 
-		 clothes_rent_status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, 0, ?, ?, ?, '0001-01-01', 1, ?, ?)`,
-		rentalID, req.IDClothingCategorySub, req.IDClothingSize, req.IDClothingCustomer, req.ClothesQtyRent, 0,
-		dateBegin, dateEnd, dateBegin, dateEnd, 1, now, now,
+		 clothes_rent_status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?, ?, '0001-01-01', ?, ?, ?)`,
+		rentalID, req.IDClothingCategorySub, req.IDClothingSize, req.IDClothingCustomer, location.ID, req.ClothesQtyRent,
+		dateBegin, dateEnd, dateBegin, utils.CLOTHES_RENT_STATUS_RENTED, now, now,
 	)
 
 	if err != nil {
@@ -129,16 +138,15 @@ func RentClothing(c *gin.Context) {
 		return
 	}
 
-	// Update inventory movement
-	inventoryID := utils.GenerateID()
-	_, err = db.DB.Exec(
-		`INSERT INTO clothing_inventory_movement (id, id_clothing_category, id_clothing_size, 
+	// Update inventory movement, the id is left to the database as transfers and imports book movements too.
+	// The total is what is left available at the location after the movement.
+	_, err = tx.Exec(
+		`INSERT INTO clothing_inventory_movement (id_clothing_category, id_clothing_size, id_clothing_location, 
          clothes_movement_action, clothes_qty_in, clothes_qty_out, clothes_qty_total, 
          clothes_cat_status_sub, created_at, updated_at) 
-         VALUES (?, ?, ?, 1, 0, ?, (SELECT COALESCE(SUM(clothes_qty_in - clothes_qty_out), 0) FROM clothing_inventory_movement 
-         WHERE id_clothing_category = ? AND id_clothing_size = ?), 1, ?, ?)`,
-		inventoryID, req.IDClothingCategorySub, req.IDClothingSize, req.ClothesQtyRent,
-		req.IDClothingCategorySub, req.IDClothingSize, now, now,
+         VALUES (?, ?, ?, ?, 0, ?, ?, ?, ?, ?)`,
+		req.IDClothingCategorySub, req.IDClothingSize, location.ID, utils.CLOTHES_MOV_ACTION_RENT, req.ClothesQtyRent,
+		available-req.ClothesQtyRent, utils.CLOTHES_MOV_STATUS_ACTIVE, now, now,
 	)
 
 	if err != nil {
@@ -146,6 +154,24 @@ func RentClothing(c *gin.Context) {
 		return
 	}
 
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, utils.AUDIT_ACTION_CREATE, utils.AUDIT_ENTITY_RENTAL, rentalID, nil, models.ClothingRental{
+		ID:                          int(rentalID),
+		IDClothingCategorySub:       req.IDClothingCategorySub,
+		IDClothingSize:              req.IDClothingSize,
+		IDClothingCustomer:          req.IDClothingCustomer,
+		IDClothingLocation:          location.ID,
+		ClothesQtyRent:              req.ClothesQtyRent,
+		ClothesRentDateBegin:        dateBegin,
+		ClothesRentDateEnd:          dateEnd,
+		ClothesRentDateActualPickup: dateBegin,
+		ClothesRentStatus:           utils.CLOTHES_RENT_STATUS_RENTED,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message":   "Rental created successfully",
 		"rental_id": rentalID,
@@ -161,14 +187,21 @@ func ReturnClothing(c *gin.Context) {
 		return
 	}
 
-	// Get rental information
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	// Get rental information, read inside the transaction so two returns of the same rental can not both pass the check
 	var rental models.ClothingRental
-	err := db.DB.QueryRow(
-		`SELECT id, id_clothing_category_sub, id_clothing_size, id_clothing_customer, 
-         clothes_qty_rent, clothes_qty_return FROM clothing_rental WHERE id = ?`,
+	err = tx.QueryRow(
+		`SELECT id, id_clothing_category_sub, id_clothing_size, id_clothing_customer, COALESCE(id_clothing_location, 0), 
+         clothes_qty_rent, clothes_qty_return, clothes_rent_status FROM clothing_rental WHERE id = ?`,
 		req.RentalID,
-	).Scan(&rental.ID, &rental.IDClothingCategorySub, &rental.IDClothingSize,
-		&rental.IDClothingCustomer, &rental.ClothesQtyRent, &rental.ClothesQtyReturn)
+	).Scan(&rental.ID, &rental.IDClothingCategorySub, &rental.IDClothingSize, &rental.IDClothingCustomer,
+		&rental.IDClothingLocation, &rental.ClothesQtyRent, &rental.ClothesQtyReturn, &rental.ClothesRentStatus)
 
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Rental not found"})
@@ -182,6 +215,21 @@ func ReturnClothing(c *gin.Context) {
 		return
 	}
 
+	// The stock comes back to the location it was handed out from unless the request names another one
+	locationID := rental.IDClothingLocation
+	if req.IDClothingLocation != 0 {
+		locationID = req.IDClothingLocation
+	}
+	location, err := activeLocation(tx, locationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if location == nil {
+		respondFieldErrors(c, http.StatusBadRequest, fieldErrors{"id_clothing_location": "is not an active location"})
+		return
+	}
+
 	now := time.Now()
 	newReturnQty := rental.ClothesQtyReturn + req.ClothesQtyReturn
 
@@ -192,7 +240,7 @@ func ReturnClothing(c *gin.Context) {
 	}
 
 	// Update rental record - Fixed: use clothes_rent_status instead of clothes_cat_status_sub
-	_, err = db.DB.Exec(
+	_, err = tx.Exec(
 		`UPDATE clothing_rental SET clothes_qty_return = ?, clothes_rent_date_actual_return = ?, 
              clothes_rent_status = ?, updated_at = ? WHERE id = ?`,
 		newReturnQty, now, newStatus, now, req.RentalID,
@@ -203,16 +251,17 @@ func ReturnClothing(c *gin.Context) {
 		return
 	}
 
-	// Update inventory movement
-	inventoryID := utils.GenerateID()
-	_, err = db.DB.Exec(
-		`INSERT INTO clothing_inventory_movement (id, id_clothing_category, id_clothing_size, 
+	// Update inventory movement, the id is left to the database as transfers and imports book movements too.
+	// The total is what is available at the location after the movement.
+	_, err = tx.Exec(
+		`INSERT INTO clothing_inventory_movement (id_clothing_category, id_clothing_size, id_clothing_location, 
          clothes_movement_action, clothes_qty_in, clothes_qty_out, clothes_qty_total, 
          clothes_cat_status_sub, created_at, updated_at) 
-         VALUES (?, ?, ?, 2, ?, 0, (SELECT COALESCE(SUM(clothes_qty_in - clothes_qty_out), 0) FROM clothing_inventory_movement 
-         WHERE id_clothing_category = ? AND id_clothing_size = ?), 1, ?, ?)`,
-		inventoryID, rental.IDClothingCategorySub, rental.IDClothingSize, req.ClothesQtyReturn,
-		rental.IDClothingCategorySub, rental.IDClothingSize, now, now,
+         VALUES (?, ?, ?, ?, ?, 0, (SELECT COALESCE(SUM(clothes_qty_in - clothes_qty_out), 0) + ? FROM clothing_inventory_movement 
+         WHERE id_clothing_size = ? AND id_clothing_location = ? AND clothes_cat_status_sub = ?), ?, ?, ?)`,
+		rental.IDClothingCategorySub, rental.IDClothingSize, location.ID, utils.CLOTHES_MOV_ACTION_RETURN, req.ClothesQtyReturn,
+		req.ClothesQtyReturn, rental.IDClothingSize, location.ID, utils.CLOTHES_MOV_STATUS_ACTIVE,
+		utils.CLOTHES_MOV_STATUS_ACTIVE, now, now,
 	)
 
 	if err != nil {
@@ -220,19 +269,62 @@ func ReturnClothing(c *gin.Context) {
 		return
 	}
 
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, utils.AUDIT_ACTION_RETURN, utils.AUDIT_ENTITY_RENTAL, int64(rental.ID),
+		gin.H{
+			"clothes_qty_return":  rental.ClothesQtyReturn,
+			"clothes_rent_status": rental.ClothesRentStatus,
+		},
+		gin.H{
+			"clothes_qty_return":              newReturnQty,
+			"clothes_rent_status":             newStatus,
+			"clothes_rent_date_actual_return": now,
+		},
+	)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Return processed successfully",
 		"status":  newStatus,
 	})
 }
 
-// GetRentals retrieves all rentals
+// rentalListSpec lists the fields GetRentals sorts and filters on, every rental status is listed by default
+var rentalListSpec = listSpec{
+	fields: map[string]listField{
+		"id":                              {"id", listFieldInt},
+		"id_clothing_category_sub":        {"id_clothing_category_sub", listFieldInt},
+		"id_clothing_size":                {"id_clothing_size", listFieldInt},
+		"id_clothing_customer":            {"id_clothing_customer", listFieldInt},
+		"id_clothing_location":            {"id_clothing_location", listFieldInt},
+		"clothes_rent_status":             {"clothes_rent_status", listFieldInt},
+		"clothes_rent_date_begin":         {"clothes_rent_date_begin", listFieldTime},
+		"clothes_rent_date_end":           {"clothes_rent_date_end", listFieldTime},
+		"clothes_rent_date_actual_pickup": {"clothes_rent_date_actual_pickup", listFieldTime},
+		"clothes_rent_date_actual_return": {"clothes_rent_date_actual_return", listFieldTime},
+		"created_at":                      {"created_at", listFieldTime},
+		"updated_at":                      {"updated_at", listFieldTime},
+	},
+	defaultSort: "-created_at",
+	statusField: "clothes_rent_status",
+	statusNames: utils.ClothesRentStatusTransReverse,
+}
+
+// GetRentals retrieves rentals a page at a time, newest first, see listSpec for the parameters
 func GetRentals(c *gin.Context) {
 	customerID := c.Query("customer_id")
-	status := c.Query("status")
+
+	list, err := parseListQuery(c, rentalListSpec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	query := `SELECT id, id_clothing_category_sub, id_clothing_size, id_clothing_customer, 
-                  clothes_qty_rent, clothes_qty_return, clothes_rent_date_begin, clothes_rent_date_end, 
+                  COALESCE(id_clothing_location, 0), clothes_qty_rent, clothes_qty_return, clothes_rent_date_begin, clothes_rent_date_end, 
                   clothes_rent_date_actual_pickup, clothes_rent_date_actual_return, clothes_rent_status, 
                   created_at, updated_at FROM clothing_rental WHERE 1=1`
 
@@ -243,13 +335,13 @@ func GetRentals(c *gin.Context) {
 		args = append(args, customerID)
 	}
 
-	if status != "" {
-		query += " AND clothes_rent_status = ?"
-		args = append(args, status)
+	query, args, err = list.listAll(c, query, args)
+	if err != nil {
+		log.Printf("Error counting rentals: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	query += " ORDER BY created_at DESC"
-
 	log.Printf("Executing query: %s with args: %v", query, args)
 
 	rows, err := db.DB.Query(query, args...)
@@ -268,7 +360,7 @@ func GetRentals(c *gin.Context) {
 		var dateBegin, dateEnd, datePickup, dateReturn, createdAt, updatedAt string
 
 		if err := rows.Scan(&rental.ID, &rental.IDClothingCategorySub, &rental.IDClothingSize,
-			&rental.IDClothingCustomer, &rental.ClothesQtyRent, &rental.ClothesQtyReturn,
+			&rental.IDClothingCustomer, &rental.IDClothingLocation, &rental.ClothesQtyRent, &rental.ClothesQtyReturn,
 			&dateBegin, &dateEnd, &datePickup, &dateReturn,
 			&rental.ClothesRentStatus, &createdAt, &updatedAt); err != nil {
 			log.Printf("Error scanning rental: %v", err)
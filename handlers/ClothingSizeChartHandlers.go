@@ -0,0 +1,663 @@
+package handlers
+
+import (
+	"clothingretail/db"
+	"clothingretail/models"
+	"clothingretail/utils"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sizeChartPropagation summarises what an updated size chart changed in the subcategories using it
+type sizeChartPropagation struct {
+	Subcategories    int `json:"subcategories"`
+	SizesCreated     int `json:"sizes_created"`
+	SizesLinked      int `json:"sizes_linked"`
+	SizesUpdated     int `json:"sizes_updated"`
+	SizesDeactivated int `json:"sizes_deactivated"`
+	// SizesDetached counts sizes of removed entries that still have rentals out or stock on hand,
+	// they stay active but no longer follow the chart
+	SizesDetached int `json:"sizes_detached"`
+}
+
+// loadSizeChartEntries reads the entries of a size chart, smallest size first
+func loadSizeChartEntries(chartID int) ([]models.ClothingSizeChartEntry, error) {
+	rows, err := db.DB.Query(
+		`SELECT id, id_clothing_size_chart, size_chart_entry_name, size_chart_entry_notes, size_chart_entry_order,
+         created_at, updated_at FROM clothing_size_chart_entry WHERE id_clothing_size_chart = ?
+         ORDER BY size_chart_entry_order, id`,
+		chartID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.ClothingSizeChartEntry{}
+	for rows.Next() {
+		var entry models.ClothingSizeChartEntry
+		if err := rows.Scan(&entry.ID, &entry.IDClothingSizeChart, &entry.SizeChartEntryName, &entry.SizeChartEntryNotes,
+			&entry.SizeChartEntryOrder, &entry.CreatedAt, &entry.UpdatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// loadSizeChart reads a single size chart with its entries, whatever its status
+func loadSizeChart(id string) (*models.ClothingSizeChart, error) {
+	var chart models.ClothingSizeChart
+	err := db.DB.QueryRow(
+		`SELECT id, size_chart_name, size_chart_notes, size_chart_status, created_at, updated_at
+         FROM clothing_size_chart WHERE id = ?`,
+		id,
+	).Scan(&chart.ID, &chart.SizeChartName, &chart.SizeChartNotes, &chart.SizeChartStatus,
+		&chart.CreatedAt, &chart.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	chart.Entries, err = loadSizeChartEntries(chart.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &chart, nil
+}
+
+// validateSizeChartRequest normalises the request and checks the chart name and its entries, whether the name
+// is taken is left to sizeChartNameTaken. It returns the http status and message to report, or 0 when the
+// request is valid.
+func validateSizeChartRequest(req *models.SizeChartRequest) (int, string) {
+	req.SizeChartName = strings.TrimSpace(req.SizeChartName)
+	req.SizeChartNotes = strings.TrimSpace(req.SizeChartNotes)
+
+	if req.SizeChartName == "" {
+		return http.StatusBadRequest, "Size chart name is required"
+	}
+
+	seen := map[string]bool{}
+	for i := range req.Entries {
+		entry := &req.Entries[i]
+		entry.SizeChartEntryName = strings.TrimSpace(entry.SizeChartEntryName)
+		entry.SizeChartEntryNotes = strings.TrimSpace(entry.SizeChartEntryNotes)
+
+		if entry.SizeChartEntryName == "" {
+			return http.StatusBadRequest, "Size name is required"
+		}
+		if utf8.RuneCountInString(entry.SizeChartEntryName) > maxSizeNameLength {
+			return http.StatusBadRequest, "Size name must be at most 8 characters"
+		}
+
+		key := strings.ToLower(entry.SizeChartEntryName)
+		if seen[key] {
+			return http.StatusBadRequest, fmt.Sprintf("Size %s appears more than once in the size chart", entry.SizeChartEntryName)
+		}
+		seen[key] = true
+	}
+
+	return 0, ""
+}
+
+// sizeChartNameTaken tells whether another size chart, active or not, already has the name. It is checked
+// inside the transaction that writes the name, so two requests cannot both take it.
+func sizeChartNameTaken(q rowQuerier, name string, excludeID int) (bool, error) {
+	var duplicates int
+	err := q.QueryRow(
+		"SELECT COUNT(*) FROM clothing_size_chart WHERE size_chart_name = ? COLLATE NOCASE AND id != ?",
+		name, excludeID,
+	).Scan(&duplicates)
+	return duplicates > 0, err
+}
+
+// applySizeChartEntry generates the size of a chart entry in a subcategory. An active size with the
+// same name is linked to the entry instead when it was entered by hand, and left alone otherwise.
+func applySizeChartEntry(tx *sql.Tx, subID int, entry models.ClothingSizeChartEntry, now time.Time) (*models.ClothingSize, bool, error) {
+	var sizeID int
+	var link sql.NullInt64
+	err := tx.QueryRow(
+		`SELECT id, id_clothing_size_chart_entry FROM clothing_size WHERE id_clothing_category_sub = ?
+         AND clothes_size_name = ? COLLATE NOCASE AND clothes_size_status = ? LIMIT 1`,
+		subID, entry.SizeChartEntryName, utils.CLOTHES_SIZE_STATUS_ACTIVE,
+	).Scan(&sizeID, &link)
+
+	if err == nil {
+		if link.Valid {
+			return nil, false, nil
+		}
+		_, err = tx.Exec(
+			"UPDATE clothing_size SET id_clothing_size_chart_entry = ?, updated_at = ? WHERE id = ?",
+			entry.ID, now, sizeID,
+		)
+		return nil, err == nil, err
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	entryID := entry.ID
+	size := models.ClothingSize{
+		IDClothingCategorySub:    subID,
+		ClothesSizeName:          entry.SizeChartEntryName,
+		ClothesSizeNotes:         entry.SizeChartEntryNotes,
+		ClothesSizeStatus:        utils.CLOTHES_SIZE_STATUS_ACTIVE,
+		IDClothingSizeChartEntry: &entryID,
+		Measurements:             []models.ClothingSizeMeasurement{},
+		CreatedAt:                now,
+		UpdatedAt:                now,
+	}
+
+	result, err := tx.Exec(
+		`INSERT INTO clothing_size (id_clothing_category_sub, clothes_size_name, clothes_size_notes,
+         clothes_size_status, id_clothing_size_chart_entry, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		size.IDClothingCategorySub, size.ClothesSizeName, size.ClothesSizeNotes,
+		size.ClothesSizeStatus, entryID, size.CreatedAt, size.UpdatedAt,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	id, _ := result.LastInsertId()
+	size.ID = int(id)
+	return &size, false, nil
+}
+
+// sizeChartSubcategories lists the subcategories holding active sizes generated from a size chart
+func sizeChartSubcategories(tx *sql.Tx, chartID int) ([]int, error) {
+	rows, err := tx.Query(
+		`SELECT DISTINCT s.id_clothing_category_sub FROM clothing_size s
+         JOIN clothing_size_chart_entry e ON e.id = s.id_clothing_size_chart_entry
+         WHERE e.id_clothing_size_chart = ? AND s.clothes_size_status = ?`,
+		chartID, utils.CLOTHES_SIZE_STATUS_ACTIVE,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subIDs []int
+	for rows.Next() {
+		var subID int
+		if err := rows.Scan(&subID); err != nil {
+			return nil, err
+		}
+		subIDs = append(subIDs, subID)
+	}
+	return subIDs, rows.Err()
+}
+
+// removeSizeChartEntry deletes an entry from its chart. With propagate the active sizes generated from it
+// are deactivated, or detached when they still have rentals out or stock on hand. Without propagate
+// all its sizes are detached and stay as they are.
+func removeSizeChartEntry(tx *sql.Tx, entryID int, propagate bool, now time.Time, summary *sizeChartPropagation) error {
+	if propagate {
+		rows, err := tx.Query(
+			"SELECT id FROM clothing_size WHERE id_clothing_size_chart_entry = ? AND clothes_size_status = ?",
+			entryID, utils.CLOTHES_SIZE_STATUS_ACTIVE,
+		)
+		if err != nil {
+			return err
+		}
+		var sizeIDs []int
+		for rows.Next() {
+			var sizeID int
+			if err := rows.Scan(&sizeID); err != nil {
+				rows.Close()
+				return err
+			}
+			sizeIDs = append(sizeIDs, sizeID)
+		}
+		rows.Close()
+
+		for _, sizeID := range sizeIDs {
+			reason, err := sizeInUse(tx, sizeID)
+			if err != nil {
+				return err
+			}
+			if reason != "" {
+				summary.SizesDetached++
+				continue
+			}
+			_, err = tx.Exec(
+				"UPDATE clothing_size SET clothes_size_status = ?, updated_at = ? WHERE id = ?",
+				utils.CLOTHES_SIZE_STATUS_INACTIVE, now, sizeID,
+			)
+			if err != nil {
+				return err
+			}
+			summary.SizesDeactivated++
+		}
+	}
+
+	_, err := tx.Exec(
+		"UPDATE clothing_size SET id_clothing_size_chart_entry = NULL, updated_at = ? WHERE id_clothing_size_chart_entry = ?",
+		now, entryID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM clothing_size_chart_entry WHERE id = ?", entryID)
+	return err
+}
+
+// GetSizeCharts retrieves all active size charts with their entries
+func GetSizeCharts(c *gin.Context) {
+	rows, err := db.DB.Query(
+		`SELECT id, size_chart_name, size_chart_notes, size_chart_status, created_at, updated_at
+         FROM clothing_size_chart WHERE size_chart_status = ? ORDER BY size_chart_name`,
+		utils.SIZE_CHART_STATUS_ACTIVE,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	charts := []models.ClothingSizeChart{}
+	for rows.Next() {
+		var chart models.ClothingSizeChart
+		if err := rows.Scan(&chart.ID, &chart.SizeChartName, &chart.SizeChartNotes, &chart.SizeChartStatus,
+			&chart.CreatedAt, &chart.UpdatedAt); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		charts = append(charts, chart)
+	}
+	rows.Close()
+
+	for i := range charts {
+		charts[i].Entries, err = loadSizeChartEntries(charts[i].ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, charts)
+}
+
+// GetSizeChartByID retrieves a single size chart with its entries
+func GetSizeChartByID(c *gin.Context) {
+	chart, err := loadSizeChart(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Size chart not found"})
+		return
+	}
+	c.JSON(http.StatusOK, chart)
+}
+
+// CreateSizeChart adds a size chart template, the order of the entries is the order of the sizes
+func CreateSizeChart(c *gin.Context) {
+	var req models.SizeChartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status, message := validateSizeChartRequest(&req); status != 0 {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	taken, err := sizeChartNameTaken(tx, req.SizeChartName, 0)
+	if err != nil {
+		log.Printf("Error checking size chart name: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate size chart"})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "Size chart name already exists"})
+		return
+	}
+
+	now := time.Now()
+	result, err := tx.Exec(
+		`INSERT INTO clothing_size_chart (size_chart_name, size_chart_notes, size_chart_status, created_at, updated_at)
+         VALUES (?, ?, ?, ?, ?)`,
+		req.SizeChartName, req.SizeChartNotes, utils.SIZE_CHART_STATUS_ACTIVE, now, now,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("Error inserting size chart: %v", err)
+		return
+	}
+	chartID, _ := result.LastInsertId()
+
+	for i, entry := range req.Entries {
+		_, err = tx.Exec(
+			`INSERT INTO clothing_size_chart_entry (id_clothing_size_chart, size_chart_entry_name, size_chart_entry_notes,
+             size_chart_entry_order, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			chartID, entry.SizeChartEntryName, entry.SizeChartEntryNotes, i+1, now, now,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			log.Printf("Error inserting size chart entry: %v", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	chart, _ := loadSizeChart(strconv.FormatInt(chartID, 10))
+	recordAudit(c, utils.AUDIT_ACTION_CREATE, utils.AUDIT_ENTITY_SIZE_CHART, chartID, nil, chart)
+
+	c.JSON(http.StatusCreated, chart)
+}
+
+// UpdateSizeChart replaces the name, notes and entries of a size chart. Entries sent with their id are kept
+// and renamed, entries without an id are added and missing entries are removed. With propagate set the
+// changes are carried over to the sizes of every subcategory the chart was applied to.
+func UpdateSizeChart(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.SizeChartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, err := loadSizeChart(id)
+	if err == sql.ErrNoRows || (err == nil && before.SizeChartStatus != utils.SIZE_CHART_STATUS_ACTIVE) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Size chart not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status, message := validateSizeChartRequest(&req); status != 0 {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	existing := map[int]bool{}
+	for _, entry := range before.Entries {
+		existing[entry.ID] = true
+	}
+	kept := map[int]bool{}
+	for _, entry := range req.Entries {
+		if entry.ID == 0 {
+			continue
+		}
+		if !existing[entry.ID] || kept[entry.ID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Entry %d does not belong to this size chart", entry.ID)})
+			return
+		}
+		kept[entry.ID] = true
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	taken, err := sizeChartNameTaken(tx, req.SizeChartName, before.ID)
+	if err != nil {
+		log.Printf("Error checking size chart name: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate size chart"})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "Size chart name already exists"})
+		return
+	}
+
+	now := time.Now()
+	var summary *sizeChartPropagation
+	var subIDs []int
+	if req.Propagate {
+		summary = &sizeChartPropagation{}
+		subIDs, err = sizeChartSubcategories(tx, before.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		summary.Subcategories = len(subIDs)
+	}
+
+	// The chart may have been deactivated since it was loaded
+	result, err := tx.Exec(
+		"UPDATE clothing_size_chart SET size_chart_name = ?, size_chart_notes = ?, updated_at = ? WHERE id = ? AND size_chart_status = ?",
+		req.SizeChartName, req.SizeChartNotes, now, before.ID, utils.SIZE_CHART_STATUS_ACTIVE,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("Error updating size chart: %v", err)
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Size chart not found"})
+		return
+	}
+
+	// Park the current names so entries can swap names without tripping the unique constraint
+	_, err = tx.Exec(
+		"UPDATE clothing_size_chart_entry SET size_chart_entry_name = '#' || id WHERE id_clothing_size_chart = ?",
+		before.ID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Without propagate removed entries only detach their sizes and there is nothing to report
+	removed := summary
+	if removed == nil {
+		removed = &sizeChartPropagation{}
+	}
+	for _, entry := range before.Entries {
+		if kept[entry.ID] {
+			continue
+		}
+		if err := removeSizeChartEntry(tx, entry.ID, req.Propagate, now, removed); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			log.Printf("Error removing size chart entry: %v", err)
+			return
+		}
+	}
+
+	var created []models.ClothingSize
+	for i, reqEntry := range req.Entries {
+		entry := models.ClothingSizeChartEntry{
+			ID:                  reqEntry.ID,
+			IDClothingSizeChart: before.ID,
+			SizeChartEntryName:  reqEntry.SizeChartEntryName,
+			SizeChartEntryNotes: reqEntry.SizeChartEntryNotes,
+			SizeChartEntryOrder: i + 1,
+		}
+
+		if entry.ID != 0 {
+			_, err = tx.Exec(
+				`UPDATE clothing_size_chart_entry SET size_chart_entry_name = ?, size_chart_entry_notes = ?,
+                 size_chart_entry_order = ?, updated_at = ? WHERE id = ?`,
+				entry.SizeChartEntryName, entry.SizeChartEntryNotes, entry.SizeChartEntryOrder, now, entry.ID,
+			)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			if summary != nil {
+				// Sizes entered by hand with the new name win, the generated size keeps its old name then
+				result, err := tx.Exec(
+					`UPDATE clothing_size SET clothes_size_name = ?, clothes_size_notes = ?, updated_at = ?
+                     WHERE id_clothing_size_chart_entry = ? AND clothes_size_status = ?
+                     AND (clothes_size_name != ? OR COALESCE(clothes_size_notes, '') != ?)
+                     AND NOT EXISTS (SELECT 1 FROM clothing_size o WHERE o.id_clothing_category_sub = clothing_size.id_clothing_category_sub
+                         AND o.id != clothing_size.id AND o.clothes_size_status = ? AND o.clothes_size_name = ? COLLATE NOCASE
+                         AND (o.id_clothing_size_chart_entry IS NULL OR o.id_clothing_size_chart_entry NOT IN
+                             (SELECT id FROM clothing_size_chart_entry WHERE id_clothing_size_chart = ?)))`,
+					entry.SizeChartEntryName, entry.SizeChartEntryNotes, now,
+					entry.ID, utils.CLOTHES_SIZE_STATUS_ACTIVE,
+					entry.SizeChartEntryName, entry.SizeChartEntryNotes,
+					utils.CLOTHES_SIZE_STATUS_ACTIVE, entry.SizeChartEntryName, before.ID,
+				)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					log.Printf("Error propagating size chart entry: %v", err)
+					return
+				}
+				updated, _ := result.RowsAffected()
+				summary.SizesUpdated += int(updated)
+			}
+			continue
+		}
+
+		result, err := tx.Exec(
+			`INSERT INTO clothing_size_chart_entry (id_clothing_size_chart, size_chart_entry_name, size_chart_entry_notes,
+             size_chart_entry_order, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			before.ID, entry.SizeChartEntryName, entry.SizeChartEntryNotes, entry.SizeChartEntryOrder, now, now,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			log.Printf("Error inserting size chart entry: %v", err)
+			return
+		}
+		entryID, _ := result.LastInsertId()
+		entry.ID = int(entryID)
+
+		if summary == nil {
+			continue
+		}
+		for _, subID := range subIDs {
+			size, linked, err := applySizeChartEntry(tx, subID, entry, now)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				log.Printf("Error propagating size chart entry: %v", err)
+				return
+			}
+			if size != nil {
+				created = append(created, *size)
+				summary.SizesCreated++
+			}
+			if linked {
+				summary.SizesLinked++
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	after, _ := loadSizeChart(id)
+	recordAudit(c, utils.AUDIT_ACTION_UPDATE, utils.AUDIT_ENTITY_SIZE_CHART, int64(before.ID), before, after)
+	for _, size := range created {
+		recordAudit(c, utils.AUDIT_ACTION_CREATE, utils.AUDIT_ENTITY_SIZE, int64(size.ID), nil, size)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"size_chart": after, "propagation": summary})
+}
+
+// DeleteSizeChart deactivates a size chart, the sizes generated from it are kept
+func DeleteSizeChart(c *gin.Context) {
+	id := c.Param("id")
+
+	before, err := loadSizeChart(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Size chart not found"})
+		return
+	}
+
+	_, err = db.DB.Exec(
+		"UPDATE clothing_size_chart SET size_chart_status = ?, updated_at = ? WHERE id = ?",
+		utils.SIZE_CHART_STATUS_INACTIVE, time.Now(), before.ID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("Error deactivating size chart: %v", err)
+		return
+	}
+
+	after, _ := loadSizeChart(id)
+	recordAudit(c, utils.AUDIT_ACTION_DELETE, utils.AUDIT_ENTITY_SIZE_CHART, int64(before.ID), before, after)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Size chart deactivated successfully"})
+}
+
+// ApplySizeChart generates the sizes of a size chart in a subcategory. Sizes the subcategory already
+// has under the same name are linked to the chart instead of being duplicated.
+func ApplySizeChart(c *gin.Context) {
+	subID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subcategory ID"})
+		return
+	}
+
+	var req models.ApplySizeChartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := loadCategorySub(c.Param("id"))
+	if err != nil || sub.ClothesCatStatusSub != utils.CAT_SUB_STATUS_ACTIVE {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subcategory not found"})
+		return
+	}
+
+	chart, err := loadSizeChart(strconv.Itoa(req.IDClothingSizeChart))
+	if err != nil || chart.SizeChartStatus != utils.SIZE_CHART_STATUS_ACTIVE {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Size chart not found"})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	created := []models.ClothingSize{}
+	linked := 0
+	for _, entry := range chart.Entries {
+		size, wasLinked, err := applySizeChartEntry(tx, subID, entry, now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			log.Printf("Error applying size chart: %v", err)
+			return
+		}
+		if size != nil {
+			created = append(created, *size)
+		}
+		if wasLinked {
+			linked++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, size := range created {
+		recordAudit(c, utils.AUDIT_ACTION_CREATE, utils.AUDIT_ENTITY_SIZE, int64(size.ID), nil, size)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"created": created, "linked": linked})
+}
@@ -0,0 +1,597 @@
+package handlers
+
+import (
+	"clothingretail/db"
+	"clothingretail/models"
+	"clothingretail/utils"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSizeNameLength mirrors the limit documented on clothing_size.clothes_size_name
+const maxSizeNameLength = 8
+
+const sizeSelectColumns = `id, id_clothing_category_sub, clothes_size_name, COALESCE(clothes_size_notes, ''),
+              clothes_size_status, id_clothing_size_chart_entry, created_at, updated_at`
+
+// sizeListSpec lists the fields GetSizes sorts and filters on
+var sizeListSpec = listSpec{
+	fields: map[string]listField{
+		"id":                       {"id", listFieldInt},
+		"id_clothing_category_sub": {"id_clothing_category_sub", listFieldInt},
+		"clothes_size_name":        {"clothes_size_name", listFieldText},
+		"clothes_size_status":      {"clothes_size_status", listFieldInt},
+		"created_at":               {"created_at", listFieldTime},
+		"updated_at":               {"updated_at", listFieldTime},
+	},
+	defaultSort:   "clothes_size_name",
+	statusField:   "clothes_size_status",
+	statusDefault: utils.CLOTHES_SIZE_STATUS_ACTIVE,
+	statusNames:   utils.ClothesSizeTransReverse,
+}
+
+// GetSizes retrieves the active sizes a page at a time, optionally filtered by subcategory, category and
+// measurements, see listSpec for the paging parameters. A measurement filter such as chest=92 keeps the sizes
+// whose range holds the value, chest_min and chest_max keep the sizes whose range overlaps the bounds.
+// Values are read and returned in unit (cm by default).
+func GetSizes(c *gin.Context) {
+	subcategoryID := c.Query("subcategory_id")
+	categoryID := c.Query("category_id")
+
+	list, err := parseListQuery(c, sizeListSpec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	unit, err := utils.NormalizeMeasurementUnit(c.Query("unit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := `SELECT ` + sizeSelectColumns + ` FROM clothing_size WHERE 1=1`
+
+	var args []interface{}
+
+	if subcategoryID != "" {
+		query += " AND id_clothing_category_sub = ?"
+		args = append(args, subcategoryID)
+	}
+
+	if categoryID != "" {
+		query += " AND id_clothing_category_sub IN (SELECT id FROM clothing_category_sub WHERE id_clothing_category = ?)"
+		args = append(args, categoryID)
+	}
+
+	clause, clauseArgs, err := sizeMeasurementFilter(c, unit, "clothing_size.id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query += clause
+	args = append(args, clauseArgs...)
+
+	query, args, err = list.listAll(c, query, args)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	// Initialize with empty slice instead of nil
+	sizes := []models.ClothingSize{}
+	var sizeIDs []int
+
+	for rows.Next() {
+		var size models.ClothingSize
+		if err := rows.Scan(&size.ID, &size.IDClothingCategorySub, &size.ClothesSizeName,
+			&size.ClothesSizeNotes, &size.ClothesSizeStatus, &size.IDClothingSizeChartEntry,
+			&size.CreatedAt, &size.UpdatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		sizes = append(sizes, size)
+		sizeIDs = append(sizeIDs, size.ID)
+	}
+	rows.Close()
+
+	measurements, err := loadSizeMeasurements(sizeIDs, unit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range sizes {
+		sizes[i].Measurements = measurements[sizes[i].ID]
+	}
+
+	c.JSON(http.StatusOK, sizes)
+}
+
+// loadSizeMeasurements reads the measurements of sizes converted to unit, keyed by size ID.
+// Every requested size gets a slice, empty when it has no measurements.
+func loadSizeMeasurements(sizeIDs []int, unit string) (map[int][]models.ClothingSizeMeasurement, error) {
+	result := make(map[int][]models.ClothingSizeMeasurement, len(sizeIDs))
+	if len(sizeIDs) == 0 {
+		return result, nil
+	}
+
+	args := make([]interface{}, len(sizeIDs))
+	for i, id := range sizeIDs {
+		args[i] = id
+		result[id] = []models.ClothingSizeMeasurement{}
+	}
+
+	rows, err := db.DB.Query(
+		`SELECT id_clothing_size, measurement_type, measurement_min_mm, measurement_max_mm
+         FROM clothing_size_measurement WHERE id_clothing_size IN (?`+strings.Repeat(", ?", len(sizeIDs)-1)+`)`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sizeID, minMM, maxMM int
+		var measurement models.ClothingSizeMeasurement
+		if err := rows.Scan(&sizeID, &measurement.MeasurementType, &minMM, &maxMM); err != nil {
+			return nil, err
+		}
+		measurement.MeasurementMin = utils.MillimetresToMeasurement(minMM, unit)
+		measurement.MeasurementMax = utils.MillimetresToMeasurement(maxMM, unit)
+		measurement.MeasurementUnit = unit
+		result[sizeID] = append(result[sizeID], measurement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Keep the measurements in the order of utils.MeasurementTypes
+	order := map[string]int{}
+	for i, measurementType := range utils.MeasurementTypes() {
+		order[measurementType] = i
+	}
+	for _, measurements := range result {
+		sort.Slice(measurements, func(i, j int) bool {
+			return order[measurements[i].MeasurementType] < order[measurements[j].MeasurementType]
+		})
+	}
+	return result, nil
+}
+
+// loadSize reads a single size by ID with its measurements in cm, whatever its status
+func loadSize(id string) (*models.ClothingSize, error) {
+	var size models.ClothingSize
+	err := db.DB.QueryRow(
+		`SELECT `+sizeSelectColumns+` FROM clothing_size WHERE id = ?`,
+		id,
+	).Scan(&size.ID, &size.IDClothingCategorySub, &size.ClothesSizeName,
+		&size.ClothesSizeNotes, &size.ClothesSizeStatus, &size.IDClothingSizeChartEntry,
+		&size.CreatedAt, &size.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	measurements, err := loadSizeMeasurements([]int{size.ID}, utils.MEASUREMENT_DEFAULT_UNIT)
+	if err != nil {
+		return nil, err
+	}
+	size.Measurements = measurements[size.ID]
+	return &size, nil
+}
+
+// sizeMeasurement is a validated measurement in millimetres, ready to be stored
+type sizeMeasurement struct {
+	measurementType string
+	minMM           int
+	maxMM           int
+}
+
+// parseSizeMeasurements validates the measurements of a size request and converts them to millimetres.
+// It returns the message to report when a measurement is invalid.
+func parseSizeMeasurements(reqs []models.SizeMeasurementRequest) ([]sizeMeasurement, string) {
+	measurements := make([]sizeMeasurement, 0, len(reqs))
+	seen := map[string]bool{}
+
+	for _, req := range reqs {
+		measurementType := strings.ToLower(strings.TrimSpace(req.MeasurementType))
+		if !utils.IsMeasurementType(measurementType) {
+			return nil, "Measurement type must be one of " + strings.Join(utils.MeasurementTypes(), ", ")
+		}
+		if seen[measurementType] {
+			return nil, "Measurement " + measurementType + " is given more than once"
+		}
+		seen[measurementType] = true
+
+		if req.MeasurementMin == nil && req.MeasurementMax == nil {
+			return nil, "Measurement " + measurementType + " needs a value"
+		}
+		minValue, maxValue := req.MeasurementMin, req.MeasurementMax
+		if minValue == nil {
+			minValue = maxValue
+		}
+		if maxValue == nil {
+			maxValue = minValue
+		}
+
+		minMM, err := utils.MeasurementToMillimetres(*minValue, req.MeasurementUnit)
+		if err != nil {
+			return nil, "Measurement " + measurementType + ": " + err.Error()
+		}
+		maxMM, err := utils.MeasurementToMillimetres(*maxValue, req.MeasurementUnit)
+		if err != nil {
+			return nil, "Measurement " + measurementType + ": " + err.Error()
+		}
+		if minMM > maxMM {
+			return nil, "Measurement " + measurementType + " has a minimum above its maximum"
+		}
+
+		measurements = append(measurements, sizeMeasurement{measurementType: measurementType, minMM: minMM, maxMM: maxMM})
+	}
+	return measurements, ""
+}
+
+// replaceSizeMeasurements swaps all measurements of a size for the given ones
+func replaceSizeMeasurements(tx *sql.Tx, sizeID int, measurements []sizeMeasurement, now time.Time) error {
+	if _, err := tx.Exec("DELETE FROM clothing_size_measurement WHERE id_clothing_size = ?", sizeID); err != nil {
+		return err
+	}
+
+	for _, measurement := range measurements {
+		_, err := tx.Exec(
+			`INSERT INTO clothing_size_measurement (id_clothing_size, measurement_type, measurement_min_mm,
+             measurement_max_mm, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			sizeID, measurement.measurementType, measurement.minMM, measurement.maxMM, now, now,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sizeMeasurementFilter builds the conditions of the measurement filters of GetSizes, values are read in unit.
+// sizeIDColumn is the size id column of the filtered query.
+func sizeMeasurementFilter(c *gin.Context, unit, sizeIDColumn string) (string, []interface{}, error) {
+	var clause strings.Builder
+	var args []interface{}
+
+	for _, measurementType := range utils.MeasurementTypes() {
+		filters := []struct {
+			param string
+			cond  string
+		}{
+			{measurementType, "m.measurement_min_mm <= ? AND m.measurement_max_mm >= ?"},
+			{measurementType + "_min", "m.measurement_max_mm >= ?"},
+			{measurementType + "_max", "m.measurement_min_mm <= ?"},
+		}
+
+		for _, filter := range filters {
+			value := c.Query(filter.param)
+			if value == "" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return "", nil, fmt.Errorf("Invalid %s", filter.param)
+			}
+			mm, err := utils.MeasurementToMillimetres(parsed, unit)
+			if err != nil {
+				return "", nil, fmt.Errorf("Invalid %s", filter.param)
+			}
+
+			clause.WriteString(` AND EXISTS (SELECT 1 FROM clothing_size_measurement m WHERE m.id_clothing_size = ` + sizeIDColumn + `
+                       AND m.measurement_type = ? AND ` + filter.cond + `)`)
+			args = append(args, measurementType)
+			for i := strings.Count(filter.cond, "?"); i > 0; i-- {
+				args = append(args, mm)
+			}
+		}
+	}
+	return clause.String(), args, nil
+}
+
+// validateSizeRequest normalises the request and checks the subcategory and name.
+// It returns the http status and message to report, or 0 when the request is valid.
+func validateSizeRequest(req *models.ClothingSizeRequest, excludeID int) (int, string) {
+	req.ClothesSizeName = strings.TrimSpace(req.ClothesSizeName)
+	req.ClothesSizeNotes = strings.TrimSpace(req.ClothesSizeNotes)
+
+	if req.ClothesSizeName == "" {
+		return http.StatusBadRequest, "Size name is required"
+	}
+	if utf8.RuneCountInString(req.ClothesSizeName) > maxSizeNameLength {
+		return http.StatusBadRequest, "Size name must be at most 8 characters"
+	}
+
+	var subStatus int
+	err := db.DB.QueryRow(
+		"SELECT clothes_cat_status_sub FROM clothing_category_sub WHERE id = ?",
+		req.IDClothingCategorySub,
+	).Scan(&subStatus)
+	if err != nil || subStatus != utils.CAT_SUB_STATUS_ACTIVE {
+		return http.StatusBadRequest, "Subcategory not found"
+	}
+
+	var duplicates int
+	err = db.DB.QueryRow(
+		`SELECT COUNT(*) FROM clothing_size WHERE id_clothing_category_sub = ?
+         AND clothes_size_name = ? COLLATE NOCASE AND clothes_size_status = ? AND id != ?`,
+		req.IDClothingCategorySub, req.ClothesSizeName, utils.CLOTHES_SIZE_STATUS_ACTIVE, excludeID,
+	).Scan(&duplicates)
+	if err != nil {
+		log.Printf("Error checking size name: %v", err)
+		return http.StatusInternalServerError, "Failed to validate size"
+	}
+	if duplicates > 0 {
+		return http.StatusConflict, "Size name already exists in this subcategory"
+	}
+
+	return 0, ""
+}
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx. With a single pooled connection,
+// checks made while a transaction is open have to go through that transaction.
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// sizeInUse tells why a size cannot be deactivated, an empty string when it is free to go
+func sizeInUse(q rowQuerier, sizeID int) (string, error) {
+	var rentedOut int
+	err := q.QueryRow(
+		`SELECT COALESCE(SUM(clothes_qty_rent - clothes_qty_return), 0) FROM clothing_rental
+         WHERE id_clothing_size = ? AND clothes_rent_status = ?`,
+		sizeID, utils.CLOTHES_RENT_STATUS_RENTED,
+	).Scan(&rentedOut)
+	if err != nil {
+		return "", err
+	}
+	if rentedOut > 0 {
+		return "Size still has rentals out", nil
+	}
+
+	var onHand int
+	err = q.QueryRow(
+		`SELECT COALESCE(SUM(clothes_qty_in - clothes_qty_out), 0) FROM clothing_inventory_movement
+         WHERE id_clothing_size = ? AND clothes_cat_status_sub = 1`,
+		sizeID,
+	).Scan(&onHand)
+	if err != nil {
+		return "", err
+	}
+	if onHand > 0 {
+		return "Size still has stock on hand", nil
+	}
+
+	return "", nil
+}
+
+// CreateSize adds a size to a subcategory
+func CreateSize(c *gin.Context) {
+	var req models.ClothingSizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status, message := validateSizeRequest(&req, 0); status != 0 {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	measurements, message := parseSizeMeasurements(req.Measurements)
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.Exec(
+		`INSERT INTO clothing_size (id_clothing_category_sub, clothes_size_name, clothes_size_notes,
+         clothes_size_status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		req.IDClothingCategorySub, req.ClothesSizeName, req.ClothesSizeNotes,
+		utils.CLOTHES_SIZE_STATUS_ACTIVE, now, now,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("Error inserting size: %v", err)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	if err := replaceSizeMeasurements(tx, int(id), measurements, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("Error inserting size measurements: %v", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	size, _ := loadSize(strconv.FormatInt(id, 10))
+	recordAudit(c, utils.AUDIT_ACTION_CREATE, utils.AUDIT_ENTITY_SIZE, id, nil, size)
+
+	c.JSON(http.StatusCreated, size)
+}
+
+// GetSizeByID retrieves a single size by ID, including inactive ones. Measurements are returned in unit (cm by default).
+func GetSizeByID(c *gin.Context) {
+	unit, err := utils.NormalizeMeasurementUnit(c.Query("unit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	size, err := loadSize(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Size not found"})
+		return
+	}
+
+	if unit != utils.MEASUREMENT_DEFAULT_UNIT {
+		measurements, err := loadSizeMeasurements([]int{size.ID}, unit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		size.Measurements = measurements[size.ID]
+	}
+
+	c.JSON(http.StatusOK, size)
+}
+
+// UpdateSize renames a size, changes its notes and measurements or moves it to another subcategory
+func UpdateSize(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.ClothingSizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, err := loadSize(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Size not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status, message := validateSizeRequest(&req, before.ID); status != 0 {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	measurements, message := parseSizeMeasurements(req.Measurements)
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	// Rentals and stock are booked against the subcategory as well, moving a size with history would split them
+	if req.IDClothingCategorySub != before.IDClothingCategorySub {
+		var history int
+		err = db.DB.QueryRow(
+			`SELECT (SELECT COUNT(*) FROM clothing_rental WHERE id_clothing_size = ?) +
+             (SELECT COUNT(*) FROM clothing_inventory_movement WHERE id_clothing_size = ?)`,
+			before.ID, before.ID,
+		).Scan(&history)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if history > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Size with rentals or stock cannot move to another subcategory"})
+			return
+		}
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.Exec(
+		`UPDATE clothing_size SET id_clothing_category_sub = ?, clothes_size_name = ?, clothes_size_notes = ?,
+         updated_at = ? WHERE id = ?`,
+		req.IDClothingCategorySub, req.ClothesSizeName, req.ClothesSizeNotes, now, before.ID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("Error updating size: %v", err)
+		return
+	}
+
+	// Measurements left out of the request are kept as they are
+	if req.Measurements != nil {
+		if err := replaceSizeMeasurements(tx, before.ID, measurements, now); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			log.Printf("Error updating size measurements: %v", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	after, _ := loadSize(id)
+	recordAudit(c, utils.AUDIT_ACTION_UPDATE, utils.AUDIT_ENTITY_SIZE, int64(before.ID), before, after)
+
+	c.JSON(http.StatusOK, after)
+}
+
+// DeleteSize deactivates a size, refused while it still has rentals out or stock on hand
+func DeleteSize(c *gin.Context) {
+	id := c.Param("id")
+
+	before, err := loadSize(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Size not found"})
+		return
+	}
+	if before.ClothesSizeStatus == utils.CLOTHES_SIZE_STATUS_INACTIVE {
+		c.JSON(http.StatusOK, gin.H{"message": "Size deactivated successfully"})
+		return
+	}
+
+	reason, err := sizeInUse(db.DB, before.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("Error checking size usage: %v", err)
+		return
+	}
+	if reason != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": reason})
+		return
+	}
+
+	_, err = db.DB.Exec(
+		"UPDATE clothing_size SET clothes_size_status = ?, updated_at = ? WHERE id = ?",
+		utils.CLOTHES_SIZE_STATUS_INACTIVE, time.Now(), before.ID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("Error deactivating size: %v", err)
+		return
+	}
+
+	after, _ := loadSize(id)
+	recordAudit(c, utils.AUDIT_ACTION_DELETE, utils.AUDIT_ENTITY_SIZE, int64(before.ID), before, after)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Size deactivated successfully"})
+}
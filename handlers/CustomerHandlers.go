@@ -3,7 +3,9 @@ package handlers
 import (
 	"clothingretail/db"
 	"clothingretail/models"
+	"clothingretail/utils"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,13 +13,19 @@ import (
 
 // CreateCustomer handles creating a new customer
 func CreateCustomer(c *gin.Context) {
-	var customer models.ClothingCustomer
-
-	if err := c.ShouldBindJSON(&customer); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req models.ClothingCustomerRequest
+	if !bindJSON(c, &req) {
 		return
 	}
 
+	customer := models.ClothingCustomer{
+		CustName:    strings.TrimSpace(req.CustName),
+		CustAddress: strings.TrimSpace(req.CustAddress),
+		CustCity:    strings.TrimSpace(req.CustCity),
+		CustPhone:   strings.TrimSpace(req.CustPhone),
+		CustEmail:   strings.TrimSpace(req.CustEmail),
+		CustNotes:   strings.TrimSpace(req.CustNotes),
+	}
 	customer.CreatedAt = time.Now()
 	customer.UpdatedAt = time.Now()
 	customer.CustStatus = 1
@@ -37,22 +45,53 @@ func CreateCustomer(c *gin.Context) {
 	id, _ := result.LastInsertId()
 	customer.ID = int(id)
 
+	recordAudit(c, utils.AUDIT_ACTION_CREATE, utils.AUDIT_ENTITY_CUSTOMER, id, nil, customer)
+
 	c.JSON(http.StatusCreated, customer)
 }
 
-// GetCustomers retrieves all customers
+// customerListSpec lists the fields GetCustomers sorts and filters on
+var customerListSpec = listSpec{
+	fields: map[string]listField{
+		"id":          {"id", listFieldInt},
+		"cust_name":   {"cust_name", listFieldText},
+		"cust_city":   {"cust_city", listFieldText},
+		"cust_phone":  {"cust_phone", listFieldText},
+		"cust_email":  {"cust_email", listFieldText},
+		"cust_status": {"cust_status", listFieldInt},
+		"created_at":  {"created_at", listFieldTime},
+		"updated_at":  {"updated_at", listFieldTime},
+	},
+	defaultSort:   "cust_name",
+	statusField:   "cust_status",
+	statusDefault: utils.CAT_CUST_STATUS_ACTIVE,
+	statusNames:   utils.CatCustTransReverse,
+}
+
+// GetCustomers retrieves the active customers a page at a time, see listSpec for the parameters
 func GetCustomers(c *gin.Context) {
-	rows, err := db.DB.Query(
+	list, err := parseListQuery(c, customerListSpec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, args, err := list.listAll(c,
 		`SELECT id, cust_name, cust_address, cust_city, cust_phone, cust_email, cust_notes, 
-         cust_status, created_at, updated_at FROM clothing_customer WHERE cust_status = 1`,
-	)
+         cust_status, created_at, updated_at FROM clothing_customer WHERE 1=1`, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := db.DB.Query(query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	defer rows.Close()
 
-	var customers []models.ClothingCustomer
+	customers := []models.ClothingCustomer{}
 	for rows.Next() {
 		var customer models.ClothingCustomer
 		if err := rows.Scan(&customer.ID, &customer.CustName, &customer.CustAddress, &customer.CustCity,
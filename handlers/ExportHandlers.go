@@ -0,0 +1,584 @@
+package handlers
+
+import (
+	"clothingretail/db"
+	"clothingretail/utils"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	exportFormatCSV  = "csv"
+	exportFormatXLSX = "xlsx"
+	exportFormatJSON = "json"
+
+	// exportFlushRows is how many rows are written before the CSV output is flushed to the client
+	exportFlushRows = 100
+
+	exportTimeFormat = "2006-01-02 15:04:05"
+	exportSheet      = "Sheet1"
+)
+
+var exportContentTypes = map[string]string{
+	exportFormatCSV:  "text/csv; charset=utf-8",
+	exportFormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	exportFormatJSON: "application/json; charset=utf-8",
+}
+
+// exportColumn is one column of an export, expr is read from the source query. names turns a status number
+// into its name.
+type exportColumn struct {
+	header string
+	expr   string
+	names  func(int) string
+}
+
+// exportSpec describes an export. The source query exposes the columns of the list spec, so an export takes
+// the same filters and sort as the list endpoint, together with the names joined in for the columns.
+// sourceArgs are bound to the placeholders of source, filter adds the parameters the list endpoint has next
+// to its listSpec.
+type exportSpec struct {
+	name       string
+	list       listSpec
+	source     string
+	sourceArgs []interface{}
+	columns    []exportColumn
+	filter     func(c *gin.Context) (string, []interface{}, error)
+}
+
+// ExportCategories streams the categories, see export for the parameters
+func ExportCategories(c *gin.Context) {
+	export(c, exportSpec{
+		name: "categories",
+		list: categoryListSpec,
+		source: `SELECT id, clothes_cat_name, COALESCE(clothes_notes, '') AS clothes_notes, clothes_cat_status,
+                 created_at, updated_at FROM clothing_category`,
+		columns: []exportColumn{
+			{header: "id", expr: "id"},
+			{header: "category", expr: "clothes_cat_name"},
+			{header: "notes", expr: "clothes_notes"},
+			{header: "status", expr: "clothes_cat_status", names: utils.CatTrans},
+			{header: "created_at", expr: "created_at"},
+			{header: "updated_at", expr: "updated_at"},
+		},
+	})
+}
+
+// ExportSubcategories streams the subcategories with their category and attributes.
+// category_id and the attr[code] filters of GetCategoriesSub apply as well.
+func ExportSubcategories(c *gin.Context) {
+	export(c, exportSpec{
+		name: "subcategories",
+		list: categorySubListSpec,
+		source: `SELECT s.id, s.id_clothing_category, c.clothes_cat_name, s.clothes_cat_name_sub, s.clothes_cat_location_sub,
+                 s.id_clothing_location, (SELECT group_concat(a.attribute_name || ': ' || v.attribute_value_name, '; ')
+                  FROM clothing_category_sub_attribute sa
+                  JOIN clothing_attribute_value v ON v.id = sa.id_clothing_attribute_value
+                  JOIN clothing_attribute a ON a.id = v.id_clothing_attribute
+                  WHERE sa.id_clothing_category_sub = s.id) AS attributes,
+                 s.clothes_cat_status_sub, s.created_at, s.updated_at
+                 FROM clothing_category_sub s JOIN clothing_category c ON c.id = s.id_clothing_category`,
+		columns: []exportColumn{
+			{header: "id", expr: "id"},
+			{header: "category", expr: "clothes_cat_name"},
+			{header: "subcategory", expr: "clothes_cat_name_sub"},
+			{header: "location", expr: "clothes_cat_location_sub"},
+			{header: "attributes", expr: "COALESCE(attributes, '')"},
+			{header: "status", expr: "clothes_cat_status_sub", names: utils.CatSubTrans},
+			{header: "created_at", expr: "created_at"},
+			{header: "updated_at", expr: "updated_at"},
+		},
+		filter: func(c *gin.Context) (string, []interface{}, error) {
+			var clause string
+			var args []interface{}
+			if categoryID := c.Query("category_id"); categoryID != "" {
+				clause += " AND id_clothing_category = ?"
+				args = append(args, categoryID)
+			}
+
+			attributes, err := loadActiveAttributes()
+			if err != nil {
+				return "", nil, err
+			}
+			filters, err := parseAttributeFilters(c, attributes)
+			if err != nil {
+				return "", nil, err
+			}
+			attributeClause, attributeArgs := attributeFilterClause(filters, "")
+			return clause + attributeClause, append(args, attributeArgs...), nil
+		},
+	})
+}
+
+// ExportSizes streams the sizes with their category, subcategory and a column per measurement in unit.
+// subcategory_id, category_id and the measurement filters of GetSizes apply as well.
+func ExportSizes(c *gin.Context) {
+	unit, err := utils.NormalizeMeasurementUnit(c.Query("unit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	columns := []exportColumn{
+		{header: "id", expr: "id"},
+		{header: "category", expr: "clothes_cat_name"},
+		{header: "subcategory", expr: "clothes_cat_name_sub"},
+		{header: "size", expr: "clothes_size_name"},
+		{header: "notes", expr: "clothes_size_notes"},
+	}
+	// A measurement reads as 92 for a single value and 92-96 for a range
+	perUnit := strconv.FormatFloat(utils.MillimetresPer(unit), 'f', -1, 64)
+	for _, measurementType := range utils.MeasurementTypes() {
+		columns = append(columns, exportColumn{
+			header: measurementType + "_" + unit,
+			expr: `COALESCE((SELECT CASE WHEN m.measurement_min_mm = m.measurement_max_mm
+                        THEN printf('%g', round(m.measurement_min_mm / ` + perUnit + `, 1))
+                        ELSE printf('%g-%g', round(m.measurement_min_mm / ` + perUnit + `, 1), round(m.measurement_max_mm / ` + perUnit + `, 1)) END
+                    FROM clothing_size_measurement m WHERE m.id_clothing_size = src.id AND m.measurement_type = '` + measurementType + `'), '')`,
+		})
+	}
+	columns = append(columns,
+		exportColumn{header: "status", expr: "clothes_size_status", names: utils.ClothesSizeTrans},
+		exportColumn{header: "created_at", expr: "created_at"},
+		exportColumn{header: "updated_at", expr: "updated_at"},
+	)
+
+	export(c, exportSpec{
+		name: "sizes",
+		list: sizeListSpec,
+		source: `SELECT z.id, z.id_clothing_category_sub, s.id_clothing_category, c.clothes_cat_name, s.clothes_cat_name_sub,
+                 z.clothes_size_name, COALESCE(z.clothes_size_notes, '') AS clothes_size_notes, z.clothes_size_status,
+                 z.created_at, z.updated_at
+                 FROM clothing_size z JOIN clothing_category_sub s ON s.id = z.id_clothing_category_sub
+                 JOIN clothing_category c ON c.id = s.id_clothing_category`,
+		columns: columns,
+		filter: func(c *gin.Context) (string, []interface{}, error) {
+			clause, args := catalogParentFilter(c)
+			measurementClause, measurementArgs, err := sizeMeasurementFilter(c, unit, "src.id")
+			if err != nil {
+				return "", nil, err
+			}
+			return clause + measurementClause, append(args, measurementArgs...), nil
+		},
+	})
+}
+
+// stockListSpec lists the fields ExportStock sorts and filters on, id is the size
+var stockListSpec = listSpec{
+	fields: map[string]listField{
+		"id":                       {"id", listFieldInt},
+		"id_clothing_category":     {"id_clothing_category", listFieldInt},
+		"id_clothing_category_sub": {"id_clothing_category_sub", listFieldInt},
+		"clothes_cat_name":         {"clothes_cat_name", listFieldText},
+		"clothes_cat_name_sub":     {"clothes_cat_name_sub", listFieldText},
+		"clothes_size_name":        {"clothes_size_name", listFieldText},
+		"clothes_size_status":      {"clothes_size_status", listFieldInt},
+		"on_hand":                  {"on_hand", listFieldInt},
+		"rented_out":               {"rented_out", listFieldInt},
+		"reserved":                 {"reserved", listFieldInt},
+		"available":                {"available", listFieldInt},
+		"id_clothing_location":     {"id_clothing_location", listFieldInt},
+		"location_name":            {"location_name", listFieldText},
+	},
+	defaultSort:   "clothes_cat_name,clothes_cat_name_sub,clothes_size_name,location_name",
+	statusField:   "clothes_size_status",
+	statusDefault: utils.CLOTHES_SIZE_STATUS_ACTIVE,
+	statusNames:   utils.ClothesSizeTransReverse,
+}
+
+// ExportStock streams the stock per size and location, counted like GetInventory does. Every size has a row for
+// the location of its subcategory and for each location it has movements or open rentals at.
+// subcategory_id and category_id apply as well.
+func ExportStock(c *gin.Context) {
+	levels, levelArgs := stockLevels("z.id", "sl.id_clothing_location", nil, time.Now())
+	export(c, exportSpec{
+		name: "stock",
+		list: stockListSpec,
+		source: `SELECT *, available + reserved AS on_hand FROM (
+                 SELECT z.id, z.id_clothing_category_sub, s.id_clothing_category, c.clothes_cat_name, s.clothes_cat_name_sub,
+                 sl.id_clothing_location, COALESCE(l.location_name, '') AS location_name, z.clothes_size_name, z.clothes_size_status,
+                 ` + levels + `
+                 FROM clothing_size z JOIN clothing_category_sub s ON s.id = z.id_clothing_category_sub
+                 JOIN clothing_category c ON c.id = s.id_clothing_category
+                 JOIN (SELECT id_clothing_size, id_clothing_location FROM clothing_inventory_movement
+                       UNION SELECT id_clothing_size, id_clothing_location FROM clothing_rental WHERE clothes_rent_status = ?
+                       UNION SELECT z2.id, s2.id_clothing_location FROM clothing_size z2
+                             JOIN clothing_category_sub s2 ON s2.id = z2.id_clothing_category_sub) sl ON sl.id_clothing_size = z.id
+                 LEFT JOIN clothing_location l ON l.id = sl.id_clothing_location)`,
+		sourceArgs: append(levelArgs, utils.CLOTHES_RENT_STATUS_RENTED),
+		columns: []exportColumn{
+			{header: "size_id", expr: "id"},
+			{header: "category", expr: "clothes_cat_name"},
+			{header: "subcategory", expr: "clothes_cat_name_sub"},
+			{header: "location", expr: "location_name"},
+			{header: "size", expr: "clothes_size_name"},
+			{header: "on_hand", expr: "on_hand"},
+			{header: "rented_out", expr: "rented_out"},
+			{header: "reserved", expr: "reserved"},
+			{header: "available", expr: "available"},
+			{header: "total", expr: "on_hand + rented_out"},
+		},
+		filter: func(c *gin.Context) (string, []interface{}, error) {
+			clause, args := catalogParentFilter(c)
+			return clause, args, nil
+		},
+	})
+}
+
+// ExportLocations streams the stores and warehouses, see export for the parameters
+func ExportLocations(c *gin.Context) {
+	export(c, exportSpec{
+		name:   "locations",
+		list:   locationListSpec,
+		source: "SELECT " + locationColumns + " FROM clothing_location",
+		columns: []exportColumn{
+			{header: "id", expr: "id"},
+			{header: "name", expr: "location_name"},
+			{header: "type", expr: "location_type", names: utils.LocationTypeTrans},
+			{header: "address", expr: "location_address"},
+			{header: "status", expr: "location_status", names: utils.LocationStatusTrans},
+			{header: "created_at", expr: "created_at"},
+			{header: "updated_at", expr: "updated_at"},
+		},
+	})
+}
+
+// ExportCustomers streams the customers, see export for the parameters
+func ExportCustomers(c *gin.Context) {
+	export(c, exportSpec{
+		name: "customers",
+		list: customerListSpec,
+		source: `SELECT id, cust_name, cust_address, cust_city, cust_phone, cust_email, COALESCE(cust_notes, '') AS cust_notes,
+                 cust_status, created_at, updated_at FROM clothing_customer`,
+		columns: []exportColumn{
+			{header: "id", expr: "id"},
+			{header: "name", expr: "cust_name"},
+			{header: "phone", expr: "cust_phone"},
+			{header: "email", expr: "cust_email"},
+			{header: "address", expr: "cust_address"},
+			{header: "city", expr: "cust_city"},
+			{header: "notes", expr: "cust_notes"},
+			{header: "status", expr: "cust_status", names: utils.CatCustTrans},
+			{header: "created_at", expr: "created_at"},
+			{header: "updated_at", expr: "updated_at"},
+		},
+	})
+}
+
+// ExportRentals streams the rentals with the names of the customer, category, subcategory and size.
+// customer_id applies as well.
+func ExportRentals(c *gin.Context) {
+	export(c, exportSpec{
+		name: "rentals",
+		list: rentalListSpec,
+		source: `SELECT r.id, r.id_clothing_category_sub, r.id_clothing_size, r.id_clothing_customer, r.id_clothing_location,
+                 COALESCE(l.location_name, '') AS location_name, COALESCE(cu.cust_name, '') AS cust_name, COALESCE(c.clothes_cat_name, '') AS clothes_cat_name,
+                 COALESCE(s.clothes_cat_name_sub, '') AS clothes_cat_name_sub, COALESCE(z.clothes_size_name, '') AS clothes_size_name,
+                 r.clothes_qty_rent, r.clothes_qty_return, r.clothes_rent_status,
+                 r.clothes_rent_date_begin, r.clothes_rent_date_end,
+                 r.clothes_rent_date_actual_pickup, r.clothes_rent_date_actual_return, r.created_at, r.updated_at
+                 FROM clothing_rental r
+                 LEFT JOIN clothing_customer cu ON cu.id = r.id_clothing_customer
+                 LEFT JOIN clothing_category_sub s ON s.id = r.id_clothing_category_sub
+                 LEFT JOIN clothing_category c ON c.id = s.id_clothing_category
+                 LEFT JOIN clothing_size z ON z.id = r.id_clothing_size
+                 LEFT JOIN clothing_location l ON l.id = r.id_clothing_location`,
+		columns: []exportColumn{
+			{header: "id", expr: "id"},
+			{header: "customer", expr: "cust_name"},
+			{header: "category", expr: "clothes_cat_name"},
+			{header: "subcategory", expr: "clothes_cat_name_sub"},
+			{header: "size", expr: "clothes_size_name"},
+			{header: "location", expr: "location_name"},
+			{header: "qty_rent", expr: "clothes_qty_rent"},
+			{header: "qty_return", expr: "clothes_qty_return"},
+			{header: "status", expr: "clothes_rent_status", names: utils.ClothesRentStatusTrans},
+			{header: "date_begin", expr: "clothes_rent_date_begin"},
+			{header: "date_end", expr: "clothes_rent_date_end"},
+			{header: "date_pickup", expr: "clothes_rent_date_actual_pickup"},
+			{header: "date_return", expr: "clothes_rent_date_actual_return"},
+			{header: "created_at", expr: "created_at"},
+			{header: "updated_at", expr: "updated_at"},
+		},
+		filter: func(c *gin.Context) (string, []interface{}, error) {
+			if customerID := c.Query("customer_id"); customerID != "" {
+				return " AND id_clothing_customer = ?", []interface{}{customerID}, nil
+			}
+			return "", nil, nil
+		},
+	})
+}
+
+// catalogParentFilter reads the subcategory_id and category_id filters of the size based exports
+func catalogParentFilter(c *gin.Context) (string, []interface{}) {
+	var clause string
+	var args []interface{}
+	if subcategoryID := c.Query("subcategory_id"); subcategoryID != "" {
+		clause += " AND id_clothing_category_sub = ?"
+		args = append(args, subcategoryID)
+	}
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		clause += " AND id_clothing_category = ?"
+		args = append(args, categoryID)
+	}
+	return clause, args
+}
+
+// export streams every row matching the filters of the list endpoint, sorted the same way, in format
+// csv (the default), xlsx or json. Rows are written as they are read, limit and cursor do not apply.
+// The rows come from a single query on the read-only pool, so the export is one consistent snapshot
+// and a slow client does not hold the connection writes go through.
+func export(c *gin.Context, spec exportSpec) {
+	format := c.DefaultQuery("format", exportFormatCSV)
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be csv, xlsx or json"})
+		return
+	}
+
+	list, err := parseListQuery(c, spec.list)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	exprs := make([]string, len(spec.columns))
+	headers := make([]string, len(spec.columns))
+	for i, column := range spec.columns {
+		exprs[i] = column.expr
+		headers[i] = column.header
+	}
+
+	query := "SELECT " + strings.Join(exprs, ", ") + " FROM (" + spec.source + ") AS src WHERE 1=1"
+	args := append([]interface{}{}, spec.sourceArgs...)
+	if spec.filter != nil {
+		clause, clauseArgs, err := spec.filter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query += clause
+		args = append(args, clauseArgs...)
+	}
+	query, args = list.filter(query, args)
+
+	query = list.order(query)
+
+	rows, err := db.ReadDB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error exporting %s: %v", spec.name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Export failed"})
+		return
+	}
+	defer rows.Close()
+
+	filename := fmt.Sprintf("%s-%s.%s", spec.name, time.Now().Format("20060102"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	// Once the first bytes are out the status cannot change anymore, failures are only logged
+	writer := newExportWriter(format, c.Writer)
+	if err := writer.header(headers); err != nil {
+		log.Printf("Error exporting %s: %v", spec.name, err)
+		return
+	}
+
+	values := make([]interface{}, len(spec.columns))
+	pointers := make([]interface{}, len(values))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			log.Printf("Error exporting %s: %v", spec.name, err)
+			return
+		}
+		row := make([]interface{}, len(values))
+		for i, value := range values {
+			row[i] = exportValue(value, spec.columns[i].names)
+		}
+		if err := writer.row(row); err != nil {
+			log.Printf("Error exporting %s: %v", spec.name, err)
+			return
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error exporting %s: %v", spec.name, err)
+		return
+	}
+	if err := writer.close(); err != nil {
+		log.Printf("Error exporting %s: %v", spec.name, err)
+	}
+}
+
+// exportValue turns a database value into what the export writes, nil stays nil
+func exportValue(value interface{}, names func(int) string) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case int64:
+		if names != nil {
+			return names(int(v))
+		}
+	case time.Time:
+		// Dates that were never set are stored as the zero date
+		if v.Year() <= 1 {
+			return nil
+		}
+		return v.In(time.Local).Format(exportTimeFormat)
+	}
+	return value
+}
+
+// exportWriter writes the rows of an export in one format
+type exportWriter interface {
+	header(headers []string) error
+	row(values []interface{}) error
+	close() error
+}
+
+func newExportWriter(format string, w io.Writer) exportWriter {
+	switch format {
+	case exportFormatXLSX:
+		return &xlsxExportWriter{w: w}
+	case exportFormatJSON:
+		return &jsonExportWriter{w: w}
+	}
+	return &csvExportWriter{w: csv.NewWriter(w)}
+}
+
+type csvExportWriter struct {
+	w    *csv.Writer
+	rows int
+}
+
+func (e *csvExportWriter) header(headers []string) error {
+	return e.w.Write(headers)
+}
+
+func (e *csvExportWriter) row(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		record[i] = fmt.Sprint(value)
+		// Text a spreadsheet would read as a formula is quoted
+		if _, isText := value.(string); isText && record[i] != "" && strings.ContainsRune("=+-@", rune(record[i][0])) {
+			record[i] = "'" + record[i]
+		}
+	}
+	if err := e.w.Write(record); err != nil {
+		return err
+	}
+
+	e.rows++
+	if e.rows%exportFlushRows == 0 {
+		e.w.Flush()
+		return e.w.Error()
+	}
+	return nil
+}
+
+func (e *csvExportWriter) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonExportWriter writes an array of objects keyed by the headers, in column order
+type jsonExportWriter struct {
+	w       io.Writer
+	headers [][]byte
+	rows    int
+}
+
+func (e *jsonExportWriter) header(headers []string) error {
+	for _, header := range headers {
+		encoded, _ := json.Marshal(header)
+		e.headers = append(e.headers, encoded)
+	}
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonExportWriter) row(values []interface{}) error {
+	var b strings.Builder
+	if e.rows > 0 {
+		b.WriteString(",")
+	}
+	b.WriteString("\n{")
+	for i, value := range values {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		b.Write(e.headers[i])
+		b.WriteString(":")
+		b.Write(encoded)
+	}
+	b.WriteString("}")
+	e.rows++
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+func (e *jsonExportWriter) close() error {
+	_, err := io.WriteString(e.w, "\n]\n")
+	return err
+}
+
+// xlsxExportWriter uses the stream writer of excelize, which moves rows to a temporary file
+// instead of keeping the whole sheet in memory
+type xlsxExportWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	line   int
+}
+
+func (e *xlsxExportWriter) header(headers []string) error {
+	e.file = excelize.NewFile()
+	stream, err := e.file.NewStreamWriter(exportSheet)
+	if err != nil {
+		return err
+	}
+	e.stream = stream
+
+	values := make([]interface{}, len(headers))
+	for i, header := range headers {
+		values[i] = header
+	}
+	return e.row(values)
+}
+
+func (e *xlsxExportWriter) row(values []interface{}) error {
+	e.line++
+	cell, err := excelize.CoordinatesToCellName(1, e.line)
+	if err != nil {
+		return err
+	}
+	return e.stream.SetRow(cell, values)
+}
+
+func (e *xlsxExportWriter) close() error {
+	defer e.file.Close()
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.w)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"clothingretail/importer"
+	"clothingretail/utils"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxImportSize   = 10 << 20
+	importFormField = "file"
+)
+
+// ImportCatalog imports categories, subcategories, sizes and opening stock from a CSV or XLSX file uploaded
+// in the "file" field, see importer.ReadRows for the columns. It is a dry run unless commit=true. The report
+// lists the errors per row and the import is only written, in one transaction, when there are none.
+func ImportCatalog(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize+(1<<20))
+	fileHeader, err := c.FormFile(importFormField)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Import file is required in the \"file\" field"})
+		return
+	}
+	if fileHeader.Size > maxImportSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Import file must be less than 10MB"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	rows, err := importer.ReadRows(fileHeader.Filename, file)
+	if err != nil {
+		if err == importer.ErrUnsupportedFormat {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	commit := c.Query("commit") == "true" || c.PostForm("commit") == "true"
+	result, err := importer.Run(rows, commit)
+	if err != nil {
+		log.Printf("Error importing %s: %v", fileHeader.Filename, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Import failed"})
+		return
+	}
+
+	for _, created := range result.Created {
+		recordAudit(c, utils.AUDIT_ACTION_CREATE, created.Entity, created.ID, nil, created.Record)
+	}
+
+	if len(result.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
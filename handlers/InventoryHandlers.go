@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"clothingretail/db"
+	"clothingretail/models"
+	"clothingretail/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stockLevels returns the available, rented_out and reserved columns for the size in sizeColumn together with
+// their arguments, see GetInventory for what they mean. location is compared with IS to the location of the
+// movements and rentals, an empty location counts every location. locationArgs are bound each time location is
+// used, so pass them when location is a placeholder.
+func stockLevels(sizeColumn, location string, locationArgs []interface{}, now time.Time) (string, []interface{}) {
+	locationCondition := func(alias string) string {
+		if location == "" {
+			return ""
+		}
+		return " AND " + alias + ".id_clothing_location IS " + location
+	}
+
+	columns := []string{
+		`COALESCE((SELECT SUM(m.clothes_qty_in - m.clothes_qty_out) FROM clothing_inventory_movement m
+          WHERE m.id_clothing_size = ` + sizeColumn + ` AND m.clothes_cat_status_sub = ?` + locationCondition("m") + `), 0) AS available`,
+		`COALESCE((SELECT SUM(r.clothes_qty_rent - r.clothes_qty_return) FROM clothing_rental r
+          WHERE r.id_clothing_size = ` + sizeColumn + ` AND r.clothes_rent_status = ? AND r.clothes_rent_date_begin <= ?` + locationCondition("r") + `), 0) AS rented_out`,
+		`COALESCE((SELECT SUM(r.clothes_qty_rent - r.clothes_qty_return) FROM clothing_rental r
+          WHERE r.id_clothing_size = ` + sizeColumn + ` AND r.clothes_rent_status = ? AND r.clothes_rent_date_begin > ?` + locationCondition("r") + `), 0) AS reserved`,
+	}
+
+	var args []interface{}
+	args = append(append(args, utils.CLOTHES_MOV_STATUS_ACTIVE), locationArgs...)
+	args = append(append(args, utils.CLOTHES_RENT_STATUS_RENTED, now), locationArgs...)
+	args = append(append(args, utils.CLOTHES_RENT_STATUS_RENTED, now), locationArgs...)
+	return strings.Join(columns, ",\n"), args
+}
+
+// inventoryListSpec lists the fields GetInventory sorts and filters on, id is the size
+var inventoryListSpec = listSpec{
+	fields: map[string]listField{
+		"id":                       {"id", listFieldInt},
+		"id_clothing_category":     {"id_clothing_category", listFieldInt},
+		"id_clothing_category_sub": {"id_clothing_category_sub", listFieldInt},
+		"clothes_cat_name":         {"clothes_cat_name", listFieldText},
+		"clothes_cat_name_sub":     {"clothes_cat_name_sub", listFieldText},
+		"clothes_size_name":        {"clothes_size_name", listFieldText},
+		"clothes_size_status":      {"clothes_size_status", listFieldInt},
+		"on_hand":                  {"on_hand", listFieldInt},
+		"rented_out":               {"rented_out", listFieldInt},
+		"reserved":                 {"reserved", listFieldInt},
+		"available":                {"available", listFieldInt},
+	},
+	defaultSort:   "clothes_cat_name,clothes_cat_name_sub,clothes_size_name",
+	statusField:   "clothes_size_status",
+	statusDefault: utils.CLOTHES_SIZE_STATUS_ACTIVE,
+	statusNames:   utils.ClothesSizeTransReverse,
+}
+
+// GetInventory reports the stock per subcategory and size, computed from the active inventory movements and the
+// open rentals. Rentals book their movement out when they are created, so the movements leave what is available
+// to rent. Open rentals that have begun are rented out, those that begin later are reserved and still on hand:
+// on_hand = available + reserved. location_id limits the stock to one location, category_id and subcategory_id
+// to part of the catalog, see listSpec for the other parameters.
+func GetInventory(c *gin.Context) {
+	list, err := parseListQuery(c, inventoryListSpec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	location := ""
+	var locationArgs []interface{}
+	if locationID := c.Query("location_id"); locationID != "" {
+		found, err := loadLocation(db.DB, locationID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Location not found"})
+			return
+		}
+		location = "?"
+		locationArgs = []interface{}{found.ID}
+	}
+
+	levels, args := stockLevels("z.id", location, locationArgs, time.Now())
+	query := `SELECT id_clothing_category, clothes_cat_name, id_clothing_category_sub, clothes_cat_name_sub, id,
+              clothes_size_name, on_hand, rented_out, reserved, available
+              FROM (SELECT *, available + reserved AS on_hand FROM (
+                  SELECT z.id, z.id_clothing_category_sub, s.id_clothing_category, c.clothes_cat_name,
+                  s.clothes_cat_name_sub, z.clothes_size_name, z.clothes_size_status, ` + levels + `
+                  FROM clothing_size z JOIN clothing_category_sub s ON s.id = z.id_clothing_category_sub
+                  JOIN clothing_category c ON c.id = s.id_clothing_category)) AS inv WHERE 1=1`
+
+	clause, clauseArgs := catalogParentFilter(c)
+	query += clause
+	args = append(args, clauseArgs...)
+
+	query, args, err = list.listAll(c, query, args)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	inventory := []models.InventoryLevel{}
+	for rows.Next() {
+		var level models.InventoryLevel
+		if err := rows.Scan(&level.IDClothingCategory, &level.ClothesCatName, &level.IDClothingCategorySub,
+			&level.ClothesCatNameSub, &level.IDClothingSize, &level.ClothesSizeName,
+			&level.OnHand, &level.RentedOut, &level.Reserved, &level.Available); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		inventory = append(inventory, level)
+	}
+
+	c.JSON(http.StatusOK, inventory)
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"clothingretail/db"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+
+	// The list metadata travels in headers so the body stays the plain array clients already read
+	listTotalHeader      = "X-Total-Count"
+	listNextCursorHeader = "X-Next-Cursor"
+
+	listCursorPrefix = "offset:"
+)
+
+type listFieldKind int
+
+const (
+	listFieldInt listFieldKind = iota
+	listFieldText
+	listFieldTime
+)
+
+// listField is a column a list endpoint can sort and filter on, exposed under its JSON field name
+type listField struct {
+	column string
+	kind   listFieldKind
+}
+
+// listSpec describes the fields of a list endpoint. Every field can be sorted on and filtered:
+// int fields by value or a comma separated list of values, text fields by exact value and
+// time fields by <name>_after and <name>_before, where a trailing _at is dropped from the name
+// (created_at becomes created_after). The status parameter filters statusField, by number or name,
+// and falls back to statusDefault; "all" or a zero statusDefault lists every status.
+type listSpec struct {
+	fields        map[string]listField
+	defaultSort   string
+	statusField   string
+	statusDefault int
+	statusNames   func(string) int
+}
+
+// listQuery is the parsed form of the list parameters of a request
+type listQuery struct {
+	conditions []string
+	args       []interface{}
+	orderBy    string
+	limit      int
+	offset     int
+}
+
+func encodeListCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(listCursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeListCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), listCursorPrefix) {
+		return 0, fmt.Errorf("Invalid cursor")
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(raw), listCursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("Invalid cursor")
+	}
+	return offset, nil
+}
+
+// parseListQuery reads limit, cursor or offset, sort, status and the field filters of a request
+func parseListQuery(c *gin.Context, spec listSpec) (*listQuery, error) {
+	q := &listQuery{limit: defaultListLimit}
+
+	if limit := c.Query("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 {
+			return nil, fmt.Errorf("Invalid limit")
+		}
+		q.limit = min(parsed, maxListLimit)
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		offset, err := decodeListCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		q.offset = offset
+	} else if offset := c.Query("offset"); offset != "" {
+		parsed, err := strconv.Atoi(offset)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("Invalid offset")
+		}
+		q.offset = parsed
+	}
+
+	sort := c.DefaultQuery("sort", spec.defaultSort)
+	var order []string
+	sortedByID := false
+	for _, key := range strings.Split(sort, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		direction := "ASC"
+		if strings.HasPrefix(key, "-") {
+			direction = "DESC"
+			key = key[1:]
+		}
+		field, ok := spec.fields[key]
+		if !ok {
+			return nil, fmt.Errorf("Unknown sort field %s", key)
+		}
+		order = append(order, field.column+" "+direction)
+		sortedByID = sortedByID || key == "id"
+	}
+	// Ties are broken by id so pages never overlap
+	if !sortedByID {
+		order = append(order, spec.fields["id"].column+" ASC")
+	}
+	q.orderBy = strings.Join(order, ", ")
+
+	if spec.statusField != "" {
+		status := spec.statusDefault
+		if value := c.Query("status"); value == "all" {
+			status = 0
+		} else if value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				parsed = spec.statusNames(strings.ToUpper(value))
+			}
+			if parsed <= 0 {
+				return nil, fmt.Errorf("Invalid status")
+			}
+			status = parsed
+		}
+		if status != 0 {
+			q.conditions = append(q.conditions, spec.fields[spec.statusField].column+" = ?")
+			q.args = append(q.args, status)
+		}
+	}
+
+	for name, field := range spec.fields {
+		if name == spec.statusField {
+			continue
+		}
+
+		switch field.kind {
+		case listFieldInt:
+			value := c.Query(name)
+			if value == "" {
+				continue
+			}
+			var values []interface{}
+			for _, part := range strings.Split(value, ",") {
+				parsed, err := strconv.Atoi(strings.TrimSpace(part))
+				if err != nil {
+					return nil, fmt.Errorf("Invalid %s", name)
+				}
+				values = append(values, parsed)
+			}
+			q.conditions = append(q.conditions, field.column+" IN (?"+strings.Repeat(", ?", len(values)-1)+")")
+			q.args = append(q.args, values...)
+
+		case listFieldText:
+			if value := c.Query(name); value != "" {
+				q.conditions = append(q.conditions, field.column+" = ?")
+				q.args = append(q.args, value)
+			}
+
+		case listFieldTime:
+			base := strings.TrimSuffix(name, "_at")
+			for _, bound := range []struct{ suffix, op string }{{"_after", ">="}, {"_before", "<="}} {
+				value := c.Query(base + bound.suffix)
+				if value == "" {
+					continue
+				}
+				t, err := parseLocalDateTime(value)
+				if err != nil {
+					return nil, fmt.Errorf("Invalid %s%s format", base, bound.suffix)
+				}
+				q.conditions = append(q.conditions, field.column+" "+bound.op+" ?")
+				q.args = append(q.args, t)
+			}
+		}
+	}
+
+	return q, nil
+}
+
+// filter adds the conditions to query, which must already end in a WHERE clause
+func (q *listQuery) filter(query string, args []interface{}) (string, []interface{}) {
+	for _, condition := range q.conditions {
+		query += " AND " + condition
+	}
+	return query, append(args, q.args...)
+}
+
+// total counts the rows matched by a filtered query
+func (q *listQuery) total(query string, args []interface{}) (int, error) {
+	var total int
+	err := db.DB.QueryRow("SELECT COUNT(*) FROM ("+query+")", args...).Scan(&total)
+	return total, err
+}
+
+// order sorts a filtered query
+func (q *listQuery) order(query string) string {
+	return query + " ORDER BY " + q.orderBy
+}
+
+// page sorts a filtered query and cuts out the requested page
+func (q *listQuery) page(query string, args []interface{}) (string, []interface{}) {
+	return q.order(query) + " LIMIT ? OFFSET ?", append(args, q.limit, q.offset)
+}
+
+// setHeaders reports the total and, when there are more rows, the cursor of the next page
+func (q *listQuery) setHeaders(c *gin.Context, total int) {
+	c.Header(listTotalHeader, strconv.Itoa(total))
+	if q.offset+q.limit < total {
+		c.Header(listNextCursorHeader, encodeListCursor(q.offset+q.limit))
+	}
+}
+
+// listAll runs the common part of a list endpoint: filter, count, set the headers and return the query of the page
+func (q *listQuery) listAll(c *gin.Context, query string, args []interface{}) (string, []interface{}, error) {
+	query, args = q.filter(query, args)
+	total, err := q.total(query, args)
+	if err != nil {
+		return "", nil, err
+	}
+	q.setHeaders(c, total)
+	query, args = q.page(query, args)
+	return query, args, nil
+}
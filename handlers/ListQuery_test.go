@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testListSpec looks like the spec of a catalog list endpoint
+var testListSpec = listSpec{
+	fields: map[string]listField{
+		"id":          {"id", listFieldInt},
+		"name":        {"item_name", listFieldText},
+		"item_status": {"item_status", listFieldInt},
+		"created_at":  {"created_at", listFieldTime},
+	},
+	defaultSort:   "name",
+	statusField:   "item_status",
+	statusDefault: 1,
+	statusNames: func(name string) int {
+		return map[string]int{"ACTIVE": 1, "INACTIVE": 2}[name]
+	},
+}
+
+func testListContext(query string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/items?"+query, nil)
+	return c
+}
+
+func TestDecodeListCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  string
+		want    int
+		wantErr bool
+	}{
+		{"first page", encodeListCursor(0), 0, false},
+		{"later page", encodeListCursor(300), 300, false},
+		{"not base64", "offset:100", 0, true},
+		{"wrong prefix", "cGFnZToxMDA", 0, true},
+		{"not a number", "b2Zmc2V0OmFiYw", 0, true},
+		{"negative offset", "b2Zmc2V0Oi0x", 0, true},
+		{"empty", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeListCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeListCursor(%q) error = %v, wantErr %v", tt.cursor, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("decodeListCursor(%q) = %d, want %d", tt.cursor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseListQuery(t *testing.T) {
+	after := time.Date(2026, 1, 2, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name       string
+		query      string
+		limit      int
+		offset     int
+		orderBy    string
+		conditions []string
+		args       []interface{}
+		wantErr    bool
+	}{
+		{
+			name:       "defaults",
+			limit:      defaultListLimit,
+			orderBy:    "item_name ASC, id ASC",
+			conditions: []string{"item_status = ?"},
+			args:       []interface{}{1},
+		},
+		{
+			name:       "limit is capped",
+			query:      "limit=5000",
+			limit:      maxListLimit,
+			orderBy:    "item_name ASC, id ASC",
+			conditions: []string{"item_status = ?"},
+			args:       []interface{}{1},
+		},
+		{
+			name:       "cursor wins over offset",
+			query:      "limit=10&offset=5&cursor=" + encodeListCursor(20),
+			limit:      10,
+			offset:     20,
+			orderBy:    "item_name ASC, id ASC",
+			conditions: []string{"item_status = ?"},
+			args:       []interface{}{1},
+		},
+		{
+			name:       "offset",
+			query:      "offset=40",
+			limit:      defaultListLimit,
+			offset:     40,
+			orderBy:    "item_name ASC, id ASC",
+			conditions: []string{"item_status = ?"},
+			args:       []interface{}{1},
+		},
+		{
+			name:       "sort by id keeps a single id key",
+			query:      "sort=-created_at,-id",
+			limit:      defaultListLimit,
+			orderBy:    "created_at DESC, id DESC",
+			conditions: []string{"item_status = ?"},
+			args:       []interface{}{1},
+		},
+		{
+			name:       "status by name",
+			query:      "status=inactive",
+			limit:      defaultListLimit,
+			orderBy:    "item_name ASC, id ASC",
+			conditions: []string{"item_status = ?"},
+			args:       []interface{}{2},
+		},
+		{
+			name:    "status all",
+			query:   "status=all",
+			limit:   defaultListLimit,
+			orderBy: "item_name ASC, id ASC",
+		},
+		{
+			name:       "int filter with a list of values",
+			query:      "status=all&id=3,%204",
+			limit:      defaultListLimit,
+			orderBy:    "item_name ASC, id ASC",
+			conditions: []string{"id IN (?, ?)"},
+			args:       []interface{}{3, 4},
+		},
+		{
+			name:       "text filter",
+			query:      "status=all&name=Kebaya",
+			limit:      defaultListLimit,
+			orderBy:    "item_name ASC, id ASC",
+			conditions: []string{"item_name = ?"},
+			args:       []interface{}{"Kebaya"},
+		},
+		{
+			name:       "time filter drops the _at suffix",
+			query:      "status=all&created_after=2026-01-02",
+			limit:      defaultListLimit,
+			orderBy:    "item_name ASC, id ASC",
+			conditions: []string{"created_at >= ?"},
+			args:       []interface{}{after},
+		},
+		{name: "zero limit", query: "limit=0", wantErr: true},
+		{name: "limit is not a number", query: "limit=ten", wantErr: true},
+		{name: "negative offset", query: "offset=-1", wantErr: true},
+		{name: "invalid cursor", query: "cursor=abc", wantErr: true},
+		{name: "unknown sort field", query: "sort=price", wantErr: true},
+		{name: "unknown status name", query: "status=archived", wantErr: true},
+		{name: "int filter is not a number", query: "id=3,x", wantErr: true},
+		{name: "invalid time filter", query: "created_before=yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parseListQuery(testListContext(tt.query), testListSpec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseListQuery(%q) returned no error", tt.query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseListQuery(%q): %v", tt.query, err)
+			}
+
+			if q.limit != tt.limit || q.offset != tt.offset {
+				t.Errorf("limit, offset = %d, %d, want %d, %d", q.limit, q.offset, tt.limit, tt.offset)
+			}
+			if q.orderBy != tt.orderBy {
+				t.Errorf("orderBy = %q, want %q", q.orderBy, tt.orderBy)
+			}
+			if !reflect.DeepEqual(q.conditions, tt.conditions) {
+				t.Errorf("conditions = %q, want %q", q.conditions, tt.conditions)
+			}
+			if len(q.args) != len(tt.args) {
+				t.Fatalf("args = %v, want %v", q.args, tt.args)
+			}
+			for i := range q.args {
+				if want, ok := tt.args[i].(time.Time); ok {
+					if got, _ := q.args[i].(time.Time); !got.Equal(want) {
+						t.Errorf("args[%d] = %v, want %v", i, q.args[i], want)
+					}
+				} else if q.args[i] != tt.args[i] {
+					t.Errorf("args[%d] = %v, want %v", i, q.args[i], tt.args[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"clothingretail/db"
+	"clothingretail/models"
+	"clothingretail/utils"
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// loginFreeAttempts is the number of failures allowed before delays kick in
+	loginFreeAttempts = 3
+
+	// loginMaxDelay caps the progressive delay between two attempts
+	loginMaxDelay = 5 * time.Minute
+
+	// loginUserLockoutThreshold failures on one username suspend that user for loginLockoutDuration
+	loginUserLockoutThreshold = 10
+
+	// loginIPLockoutThreshold failures from one client IP block that IP for loginLockoutDuration
+	loginIPLockoutThreshold = 20
+
+	loginLockoutDuration = 15 * time.Minute
+)
+
+// loginAttemptKeys returns the username and client IP keys a login attempt is counted against
+func loginAttemptKeys(username, clientIP string) map[int]string {
+	return map[int]string{
+		utils.LOGIN_ATTEMPT_KEY_USERNAME: strings.ToLower(strings.TrimSpace(username)),
+		utils.LOGIN_ATTEMPT_KEY_IP:       clientIP,
+	}
+}
+
+// loginDelay returns how long a key has to wait after failedCount consecutive failures
+func loginDelay(failedCount int) time.Duration {
+	if failedCount < loginFreeAttempts {
+		return 0
+	}
+	shift := failedCount - loginFreeAttempts
+	if shift > 16 {
+		return loginMaxDelay
+	}
+	delay := time.Second << uint(shift)
+	if delay > loginMaxDelay {
+		delay = loginMaxDelay
+	}
+	return delay
+}
+
+// loginRetryAfter returns how long the caller has to wait before it may try to log in again
+func loginRetryAfter(q rowQuerier, username, clientIP string) (time.Duration, error) {
+	now := time.Now()
+	var wait time.Duration
+
+	for keyType, key := range loginAttemptKeys(username, clientIP) {
+		var failedCount int
+		var lastFailedAt time.Time
+		var lockedUntil sql.NullTime
+
+		err := q.QueryRow(
+			`SELECT attempt_failed_count, attempt_last_failed_at, attempt_locked_until
+             FROM clothing_login_attempts WHERE attempt_key_type = ? AND attempt_key = ?`,
+			keyType, key,
+		).Scan(&failedCount, &lastFailedAt, &lockedUntil)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if lockedUntil.Valid && lockedUntil.Time.After(now) {
+			if d := lockedUntil.Time.Sub(now); d > wait {
+				wait = d
+			}
+			continue
+		}
+
+		if d := lastFailedAt.Add(loginDelay(failedCount)).Sub(now); d > wait {
+			wait = d
+		}
+	}
+
+	return wait, nil
+}
+
+// countLoginFailure bumps the failure counter of one key and returns its new value
+func countLoginFailure(q rowQuerier, keyType int, key string, now time.Time) (int, error) {
+	var failedCount int
+	err := q.QueryRow(
+		`INSERT INTO clothing_login_attempts (attempt_key_type, attempt_key, attempt_failed_count,
+         attempt_last_failed_at, created_at, updated_at) VALUES (?, ?, 1, ?, ?, ?)
+         ON CONFLICT (attempt_key_type, attempt_key) DO UPDATE SET
+         attempt_failed_count = attempt_failed_count + 1, attempt_last_failed_at = excluded.attempt_last_failed_at,
+         updated_at = excluded.updated_at
+         RETURNING attempt_failed_count`,
+		keyType, key, now, now, now,
+	).Scan(&failedCount)
+	return failedCount, err
+}
+
+// claimLoginAttempt counts a login attempt as failed before its PIN is verified, so that requests sent
+// in parallel cannot all pass the throttle on the same count. It returns how long the caller has to wait
+// instead when the username or client IP is throttled or locked out, and the failure counts otherwise.
+// A successful login clears the counts again.
+func claimLoginAttempt(username, clientIP string) (time.Duration, map[int]int, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	wait, err := loginRetryAfter(tx, username, clientIP)
+	if err != nil || wait > 0 {
+		return wait, nil, err
+	}
+
+	now := time.Now()
+	counts := make(map[int]int)
+	for keyType, key := range loginAttemptKeys(username, clientIP) {
+		if counts[keyType], err = countLoginFailure(tx, keyType, key, now); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return 0, counts, tx.Commit()
+}
+
+// releaseExpiredLockout reactivates a user whose lockout suspension has run out
+func releaseExpiredLockout(username string) error {
+	key := strings.ToLower(strings.TrimSpace(username))
+
+	var id int
+	var lockedUntil sql.NullTime
+	err := db.DB.QueryRow(
+		`SELECT id, attempt_locked_until FROM clothing_login_attempts
+         WHERE attempt_key_type = ? AND attempt_key = ? AND attempt_suspended_user = 1`,
+		utils.LOGIN_ATTEMPT_KEY_USERNAME, key,
+	).Scan(&id, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if lockedUntil.Valid && lockedUntil.Time.After(time.Now()) {
+		return nil
+	}
+
+	return clearLockout(id)
+}
+
+// recordLoginFailure bumps the failure counters and locks out keys that crossed their threshold
+func recordLoginFailure(username, clientIP string) {
+	now := time.Now()
+	counts := make(map[int]int)
+
+	for keyType, key := range loginAttemptKeys(username, clientIP) {
+		failedCount, err := countLoginFailure(db.DB, keyType, key, now)
+		if err != nil {
+			log.Printf("Error recording failed login for %s: %v", key, err)
+			continue
+		}
+		counts[keyType] = failedCount
+	}
+
+	lockOutLoginKeys(username, clientIP, counts)
+}
+
+// lockOutLoginKeys locks out the keys whose failure count crossed their threshold
+func lockOutLoginKeys(username, clientIP string, counts map[int]int) {
+	now := time.Now()
+
+	for keyType, key := range loginAttemptKeys(username, clientIP) {
+		failedCount, ok := counts[keyType]
+		if !ok {
+			continue
+		}
+
+		threshold := loginIPLockoutThreshold
+		if keyType == utils.LOGIN_ATTEMPT_KEY_USERNAME {
+			threshold = loginUserLockoutThreshold
+		}
+		if failedCount < threshold {
+			continue
+		}
+
+		suspended := 0
+		var suspendedAt interface{}
+		if keyType == utils.LOGIN_ATTEMPT_KEY_USERNAME {
+			var userID int
+			err := db.DB.QueryRow(
+				`UPDATE clothing_users SET user_status = ?, updated_at = ? WHERE username = ? COLLATE NOCASE AND user_status = ?
+                 RETURNING id`,
+				utils.CLOTHES_USER_STATUS_SUSPENDED, now, key, utils.CLOTHES_USER_STATUS_ACTIVE,
+			).Scan(&userID)
+			if err != nil && err != sql.ErrNoRows {
+				log.Printf("Error suspending user %s: %v", key, err)
+			} else if err == nil {
+				suspended, suspendedAt = 1, now
+				log.Printf("User %s suspended after %d failed logins", key, failedCount)
+
+				// Whoever is guessing the PIN must not be able to ride on a session that is already open
+				if _, err := revokeUserSessions(userID, 0); err != nil {
+					log.Printf("Error revoking sessions of user %s: %v", key, err)
+				}
+			}
+		}
+
+		// attempt_suspended_at keeps the updated_at written to the user, clearLockout compares against it
+		_, err := db.DB.Exec(
+			`UPDATE clothing_login_attempts SET attempt_locked_until = ?,
+             attempt_suspended_user = MAX(attempt_suspended_user, ?),
+             attempt_suspended_at = COALESCE(?, attempt_suspended_at), updated_at = ?
+             WHERE attempt_key_type = ? AND attempt_key = ?`,
+			now.Add(loginLockoutDuration), suspended, suspendedAt, now, keyType, key,
+		)
+		if err != nil {
+			log.Printf("Error locking out %s: %v", key, err)
+		}
+	}
+}
+
+// clearLoginAttempts resets the counters after a successful login
+func clearLoginAttempts(username, clientIP string) {
+	for keyType, key := range loginAttemptKeys(username, clientIP) {
+		_, err := db.DB.Exec(
+			"DELETE FROM clothing_login_attempts WHERE attempt_key_type = ? AND attempt_key = ?",
+			keyType, key,
+		)
+		if err != nil {
+			log.Printf("Error clearing login attempts for %s: %v", key, err)
+		}
+	}
+}
+
+// clearLockout removes a login attempt record and lifts the suspension it caused. A user changed since the
+// lockout suspended them, for instance suspended by hand, keeps their status.
+func clearLockout(id int) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var keyType, suspended int
+	var key string
+	err = tx.QueryRow(
+		"SELECT attempt_key_type, attempt_key, attempt_suspended_user FROM clothing_login_attempts WHERE id = ?",
+		id,
+	).Scan(&keyType, &key, &suspended)
+	if err != nil {
+		return err
+	}
+
+	if keyType == utils.LOGIN_ATTEMPT_KEY_USERNAME && suspended == 1 {
+		_, err = tx.Exec(
+			`UPDATE clothing_users SET user_status = ?, updated_at = ? WHERE username = ? COLLATE NOCASE AND user_status = ?
+             AND updated_at = (SELECT attempt_suspended_at FROM clothing_login_attempts WHERE id = ?)`,
+			utils.CLOTHES_USER_STATUS_ACTIVE, time.Now(), key, utils.CLOTHES_USER_STATUS_SUSPENDED, id,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err = tx.Exec("DELETE FROM clothing_login_attempts WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetLockouts lists usernames and client IPs with failed logins, locked ones first
+func GetLockouts(c *gin.Context) {
+	rows, err := db.DB.Query(
+		`SELECT id, attempt_key_type, attempt_key, attempt_failed_count, attempt_last_failed_at,
+         attempt_locked_until, attempt_suspended_user, created_at, updated_at
+         FROM clothing_login_attempts ORDER BY attempt_locked_until IS NULL, attempt_last_failed_at DESC`,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	lockouts := []models.ClothingLoginAttempt{}
+	for rows.Next() {
+		var attempt models.ClothingLoginAttempt
+		var lockedUntil sql.NullTime
+		var suspended int
+
+		if err := rows.Scan(&attempt.ID, &attempt.AttemptKeyType, &attempt.AttemptKey, &attempt.AttemptFailedCount,
+			&attempt.AttemptLastFailedAt, &lockedUntil, &suspended, &attempt.CreatedAt, &attempt.UpdatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		attempt.AttemptKeyTypeStr = utils.LoginAttemptKeyTrans(attempt.AttemptKeyType)
+		if lockedUntil.Valid {
+			attempt.AttemptLockedUntil = &lockedUntil.Time
+		}
+		attempt.AttemptSuspendedUser = suspended == 1
+		lockouts = append(lockouts, attempt)
+	}
+
+	c.JSON(http.StatusOK, lockouts)
+}
+
+// ClearLockout resets the failed logins of one username or client IP
+func ClearLockout(c *gin.Context) {
+	id := c.Param("id")
+
+	var attemptID int
+	if err := db.DB.QueryRow("SELECT id FROM clothing_login_attempts WHERE id = ?", id).Scan(&attemptID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lockout not found"})
+		return
+	}
+
+	if err := clearLockout(attemptID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Lockout cleared successfully"})
+}
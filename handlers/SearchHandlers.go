@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"clothingretail/db"
+	"clothingretail/models"
+	"clothingretail/utils"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+
+	// searchSnippetTokens is the amount of words around the match kept in a snippet
+	searchSnippetTokens = 12
+
+	// The snippets are marked with control characters so the text can be escaped before the marks become HTML
+	searchMarkOpen  = "\x02"
+	searchMarkClose = "\x03"
+)
+
+// searchSource is one full-text index. The query takes the MATCH expression and the limit and returns
+// id, title, subtitle, snippet and rank, where a lower rank is a better match.
+type searchSource struct {
+	key        string
+	permission string
+	query      string
+}
+
+func searchSources() []searchSource {
+	snippet := func(table string) string {
+		return "snippet(" + table + ", -1, char(2), char(3), '…', " + strconv.Itoa(searchSnippetTokens) + ")"
+	}
+
+	return []searchSource{
+		{
+			key:        "categories",
+			permission: utils.PERM_CATALOG_VIEW,
+			query: `SELECT c.id, c.clothes_cat_name, '', ` + snippet("clothing_category_fts") + `, rank
+                    FROM clothing_category_fts JOIN clothing_category c ON c.id = clothing_category_fts.rowid
+                    WHERE clothing_category_fts MATCH ? AND c.clothes_cat_status = 1 ORDER BY rank LIMIT ?`,
+		},
+		{
+			key:        "subcategories",
+			permission: utils.PERM_CATALOG_VIEW,
+			query: `SELECT s.id, s.clothes_cat_name_sub, s.clothes_cat_location_sub, ` + snippet("clothing_category_sub_fts") + `, rank
+                    FROM clothing_category_sub_fts JOIN clothing_category_sub s ON s.id = clothing_category_sub_fts.rowid
+                    WHERE clothing_category_sub_fts MATCH ? AND s.clothes_cat_status_sub = 1 ORDER BY rank LIMIT ?`,
+		},
+		{
+			key:        "customers",
+			permission: utils.PERM_CUSTOMER_VIEW,
+			query: `SELECT cu.id, cu.cust_name, cu.cust_phone, ` + snippet("clothing_customer_fts") + `, rank
+                    FROM clothing_customer_fts JOIN clothing_customer cu ON cu.id = clothing_customer_fts.rowid
+                    WHERE clothing_customer_fts MATCH ? AND cu.cust_status = 1 ORDER BY rank LIMIT ?`,
+		},
+		{
+			key:        "rentals",
+			permission: utils.PERM_RENTAL_VIEW,
+			query: `SELECT r.id, clothing_rental_fts.cust_name,
+                    clothing_rental_fts.clothes_cat_name_sub || ' / ' || clothing_rental_fts.clothes_size_name,
+                    ` + snippet("clothing_rental_fts") + `, rank
+                    FROM clothing_rental_fts JOIN clothing_rental r ON r.id = clothing_rental_fts.rowid
+                    WHERE clothing_rental_fts MATCH ? ORDER BY rank, r.clothes_rent_date_begin DESC LIMIT ?`,
+		},
+	}
+}
+
+// searchMatchExpression turns free text into an FTS5 expression where every word has to match as a prefix.
+// Words are split the way the unicode61 tokenizer splits them and quoted, so no FTS5 syntax gets through.
+func searchMatchExpression(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, `"`+word+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// searchSnippetHTML escapes a snippet and turns the match marks into <mark> elements
+func searchSnippetHTML(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, searchMarkOpen, "<mark>")
+	return strings.ReplaceAll(escaped, searchMarkClose, "</mark>")
+}
+
+// Search looks up q in categories, subcategories, customers and rentals, best matches first.
+// types limits the search to a comma separated list of those, by default every type the caller may view is searched.
+func Search(c *gin.Context) {
+	match := searchMatchExpression(c.Query("q"))
+	if match == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search text is required"})
+		return
+	}
+
+	limit := defaultSearchLimit
+	if l := c.Query("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = min(parsed, maxSearchLimit)
+	}
+
+	requested := map[string]bool{}
+	if types := c.Query("types"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			requested[strings.TrimSpace(t)] = true
+		}
+	}
+
+	sources := searchSources()
+	known := map[string]bool{}
+	for _, source := range sources {
+		known[source.key] = true
+	}
+	for t := range requested {
+		if !known[t] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown search type " + t})
+			return
+		}
+	}
+
+	results := gin.H{"query": c.Query("q")}
+	for _, source := range sources {
+		if len(requested) > 0 && !requested[source.key] {
+			continue
+		}
+		if _, ok := callerPermitted(c, source.permission); !ok {
+			// Asking for a type explicitly without the permission is refused, otherwise it is left out
+			if requested[source.key] {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+				return
+			}
+			continue
+		}
+
+		rows, err := db.DB.Query(source.query, match, limit)
+		if err != nil {
+			log.Printf("Error searching %s: %v", source.key, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+			return
+		}
+
+		hits := []models.SearchHit{}
+		for rows.Next() {
+			var hit models.SearchHit
+			var snippet string
+			var rank float64
+			if err := rows.Scan(&hit.ID, &hit.Title, &hit.Subtitle, &snippet, &rank); err != nil {
+				rows.Close()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			hit.Snippet = searchSnippetHTML(snippet)
+			// bm25 ranks are negative with the best match lowest, flip them into a score
+			hit.Score = -rank
+			hits = append(hits, hit)
+		}
+		rows.Close()
+
+		results[source.key] = hits
+	}
+
+	c.JSON(http.StatusOK, results)
+}
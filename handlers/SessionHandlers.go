@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"clothingretail/conf"
+	"clothingretail/db"
+	"clothingretail/models"
+	"clothingretail/utils"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sessionCookieName = "user_session"
+
+	// sessionTokenBytes is the amount of random bytes behind every session token
+	sessionTokenBytes = 32
+
+	// sessionTouchInterval limits how often a session row is rewritten while it is being used
+	sessionTouchInterval = time.Minute
+
+	defaultSessionMaxAge         = 3600
+	defaultSessionAbsoluteMaxAge = 43200
+)
+
+var errSessionInvalid = errors.New("session is invalid or expired")
+
+// sessionMaxAge returns the idle timeout of a session
+func sessionMaxAge() time.Duration {
+	seconds := conf.Koan.Int(conf.RunMode + ".session_max_age")
+	if seconds <= 0 {
+		seconds = defaultSessionMaxAge
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// sessionAbsoluteMaxAge returns the lifetime of a session regardless of activity
+func sessionAbsoluteMaxAge() time.Duration {
+	seconds := conf.Koan.Int(conf.RunMode + ".session_absolute_max_age")
+	if seconds <= 0 {
+		seconds = defaultSessionAbsoluteMaxAge
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// sessionExpiry returns the next expiry for a session created at createdAt, capped by the absolute lifetime
+func sessionExpiry(createdAt, now time.Time) time.Time {
+	expiresAt := now.Add(sessionMaxAge())
+	if limit := createdAt.Add(sessionAbsoluteMaxAge()); expiresAt.After(limit) {
+		expiresAt = limit
+	}
+	return expiresAt
+}
+
+// cookieSecure reports whether cookies are restricted to https
+func cookieSecure() bool {
+	return conf.Koan.Bool(conf.RunMode + ".cookie_secure")
+}
+
+// cookieSameSite returns the configured SameSite mode, Lax unless configured otherwise
+func cookieSameSite() http.SameSite {
+	switch strings.ToLower(conf.Koan.String(conf.RunMode + ".cookie_samesite")) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// setSessionCookie writes the session cookie, a negative maxAge removes it
+func setSessionCookie(c *gin.Context, token string, maxAge int) {
+	c.SetSameSite(cookieSameSite())
+	c.SetCookie(
+		sessionCookieName,
+		token,
+		maxAge,
+		"/",            // Path
+		"",             // Domain
+		cookieSecure(), // Secure
+		true,           // HttpOnly
+	)
+}
+
+// createSession stores a new session for the user and returns the raw token for the cookie
+func createSession(c *gin.Context, userID int) (string, error) {
+	token, err := utils.GenerateToken(sessionTokenBytes)
+	if err != nil {
+		return "", err
+	}
+
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 256 {
+		userAgent = userAgent[:256]
+	}
+
+	csrfToken, err := utils.GenerateToken(csrfTokenBytes)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	_, err = db.DB.Exec(
+		`INSERT INTO clothing_sessions (id_clothing_users, session_token_hash, session_csrf_hash, session_user_agent,
+         session_client_ip, session_status, session_expires_at, session_last_seen_at, created_at, updated_at)
+         VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		userID, utils.HashToken(token), utils.HashToken(csrfToken), userAgent, c.ClientIP(), utils.SESSION_STATUS_ACTIVE,
+		sessionExpiry(now, now), now, now, now,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	setCsrfCookie(c, csrfToken, 0)
+	return token, nil
+}
+
+// lookupSession returns the active session behind a raw token, as long as its user is still active
+func lookupSession(token string) (*models.ClothingSession, error) {
+	var session models.ClothingSession
+
+	err := db.DB.QueryRow(
+		`SELECT s.id, s.id_clothing_users, s.session_user_agent, s.session_client_ip, s.session_status,
+         s.session_expires_at, s.session_last_seen_at, s.created_at, s.updated_at
+         FROM clothing_sessions s JOIN clothing_users u ON u.id = s.id_clothing_users
+         WHERE s.session_token_hash = ? AND s.session_status = ? AND u.user_status = ?`,
+		utils.HashToken(token), utils.SESSION_STATUS_ACTIVE, utils.CLOTHES_USER_STATUS_ACTIVE,
+	).Scan(&session.ID, &session.IDClothingUsers, &session.SessionUserAgent, &session.SessionClientIP,
+		&session.SessionStatus, &session.SessionExpiresAt, &session.SessionLastSeenAt,
+		&session.CreatedAt, &session.UpdatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errSessionInvalid
+		}
+		return nil, err
+	}
+
+	if time.Now().After(session.SessionExpiresAt) {
+		_, _ = db.DB.Exec(
+			"UPDATE clothing_sessions SET session_status = ?, updated_at = ? WHERE id = ?",
+			utils.SESSION_STATUS_EXPIRED, time.Now(), session.ID,
+		)
+		return nil, errSessionInvalid
+	}
+
+	return &session, nil
+}
+
+// renewSession slides the expiry of a session forward and refreshes the cookie
+func renewSession(c *gin.Context, session *models.ClothingSession, token string) error {
+	now := time.Now()
+	if now.Sub(session.SessionLastSeenAt) < sessionTouchInterval {
+		return nil
+	}
+
+	expiresAt := sessionExpiry(session.CreatedAt, now)
+	_, err := db.DB.Exec(
+		`UPDATE clothing_sessions SET session_expires_at = ?, session_last_seen_at = ?, session_client_ip = ?,
+         updated_at = ? WHERE id = ?`,
+		expiresAt, now, c.ClientIP(), now, session.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	setSessionCookie(c, token, int(expiresAt.Sub(now).Seconds()))
+	return nil
+}
+
+// revokeSession invalidates the session behind a raw token
+func revokeSession(token string) error {
+	_, err := db.DB.Exec(
+		"UPDATE clothing_sessions SET session_status = ?, updated_at = ? WHERE session_token_hash = ? AND session_status = ?",
+		utils.SESSION_STATUS_REVOKED, time.Now(), utils.HashToken(token), utils.SESSION_STATUS_ACTIVE,
+	)
+	return err
+}
+
+// revokeUserSessions invalidates every active session of a user except exceptID, pass 0 to revoke them all
+func revokeUserSessions(userID, exceptID int) (int64, error) {
+	result, err := db.DB.Exec(
+		`UPDATE clothing_sessions SET session_status = ?, updated_at = ?
+         WHERE id_clothing_users = ? AND session_status = ? AND id != ?`,
+		utils.SESSION_STATUS_REVOKED, time.Now(), userID, utils.SESSION_STATUS_ACTIVE, exceptID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// listSessions returns the sessions of userID that are still usable, most recently used first
+func listSessions(c *gin.Context, userID int) {
+	rows, err := db.DB.Query(
+		`SELECT id, id_clothing_users, session_user_agent, session_client_ip, session_status,
+         session_expires_at, session_last_seen_at, created_at, updated_at
+         FROM clothing_sessions WHERE id_clothing_users = ? AND session_status = ? AND session_expires_at > ?
+         ORDER BY session_last_seen_at DESC`,
+		userID, utils.SESSION_STATUS_ACTIVE, time.Now(),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	sessions := []models.ClothingSession{}
+	for rows.Next() {
+		var session models.ClothingSession
+		if err := rows.Scan(&session.ID, &session.IDClothingUsers, &session.SessionUserAgent, &session.SessionClientIP,
+			&session.SessionStatus, &session.SessionExpiresAt, &session.SessionLastSeenAt,
+			&session.CreatedAt, &session.UpdatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		session.SessionStatusStr = utils.SessionStatusTrans(session.SessionStatus)
+		session.Current = session.ID == c.GetInt("session_id")
+		sessions = append(sessions, session)
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// revokeSessionOf revokes one active session, as long as it belongs to userID
+func revokeSessionOf(c *gin.Context, userID int, sessionID string) {
+	result, err := db.DB.Exec(
+		`UPDATE clothing_sessions SET session_status = ?, updated_at = ?
+         WHERE id = ? AND id_clothing_users = ? AND session_status = ?`,
+		utils.SESSION_STATUS_REVOKED, time.Now(), sessionID, userID, utils.SESSION_STATUS_ACTIVE,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	// Revoking the session the request came in with is a logout
+	if strconv.Itoa(c.GetInt("session_id")) == sessionID {
+		setSessionCookie(c, "", -1)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// GetSessions lists the active sessions of the authenticated user
+func GetSessions(c *gin.Context) {
+	listSessions(c, c.GetInt("user_id"))
+}
+
+// RevokeSession revokes one of the authenticated user's sessions
+func RevokeSession(c *gin.Context) {
+	revokeSessionOf(c, c.GetInt("user_id"), c.Param("id"))
+}
+
+// RevokeOtherSessions signs the authenticated user out everywhere except the current session
+func RevokeOtherSessions(c *gin.Context) {
+	revoked, err := revokeUserSessions(c.GetInt("user_id"), c.GetInt("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sessions revoked successfully",
+		"revoked": revoked,
+	})
+}
+
+// GetUserSessions lists the active sessions of another user
+func GetUserSessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	listSessions(c, userID)
+}
+
+// RevokeUserSession revokes a single session of another user, e.g. a lost tablet
+func RevokeUserSession(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	revokeSessionOf(c, userID, c.Param("session_id"))
+}
+
+// RevokeUserSessions signs another user out of every device
+func RevokeUserSessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Keep the caller's own session alive when they sign themselves out elsewhere
+	exceptID := 0
+	if userID == c.GetInt("user_id") {
+		exceptID = c.GetInt("session_id")
+	}
+
+	revoked, err := revokeUserSessions(userID, exceptID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sessions revoked successfully",
+		"revoked": revoked,
+	})
+}
@@ -0,0 +1,445 @@
+package handlers
+
+import (
+	"clothingretail/conf"
+	"clothingretail/db"
+	"clothingretail/models"
+	"clothingretail/utils"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	// loginChallengeDuration is how long a user has to enter the TOTP code after the PIN step
+	loginChallengeDuration = 5 * time.Minute
+
+	// loginChallengeMaxAttempts wrong codes invalidate the challenge, the PIN has to be entered again
+	loginChallengeMaxAttempts = 5
+
+	// recoveryCodeCount is the number of recovery codes handed out on enrollment
+	recoveryCodeCount = 10
+
+	// totpQRSize is the width and height in pixels of the provisioning QR code
+	totpQRSize = 256
+)
+
+var errChallengeInvalid = errors.New("login challenge is invalid or expired")
+
+// totpIssuer is the name authenticator apps show next to the code
+func totpIssuer() string {
+	if issuer := conf.Koan.String("appname"); issuer != "" {
+		return issuer
+	}
+	return "clothingretail"
+}
+
+// totpAllowedRole reports whether a role may enroll in TOTP, it is meant for privileged accounts
+func totpAllowedRole(role int) bool {
+	return role == utils.USER_ROLE_OWNER || role == utils.USER_ROLE_MANAGER
+}
+
+// createLoginChallenge starts the second login step for a user who passed the PIN step
+func createLoginChallenge(userID int) (string, error) {
+	token, err := utils.GenerateToken(sessionTokenBytes)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	// Drop challenges that were never completed
+	if _, err := db.DB.Exec("DELETE FROM clothing_login_challenges WHERE challenge_expires_at < ?", now); err != nil {
+		log.Printf("Error removing expired login challenges: %v", err)
+	}
+
+	_, err = db.DB.Exec(
+		`INSERT INTO clothing_login_challenges (id_clothing_users, challenge_token_hash, challenge_attempts,
+         challenge_expires_at, created_at) VALUES (?, ?, 0, ?, ?)`,
+		userID, utils.HashToken(token), now.Add(loginChallengeDuration), now,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// verifyTotpOrRecoveryCode checks a TOTP code, or consumes a recovery code, for a user
+func verifyTotpOrRecoveryCode(userID int, code string) (bool, error) {
+	if _, err := strconv.Atoi(code); err == nil && len(code) == utils.TOTP_DIGITS {
+		var secret string
+		var lastStep int64
+		err := db.DB.QueryRow(
+			"SELECT totp_secret, totp_last_step FROM clothing_users WHERE id = ? AND totp_enabled = 1",
+			userID,
+		).Scan(&secret, &lastStep)
+		if err != nil {
+			return false, err
+		}
+
+		step, ok := utils.VerifyTotp(secret, code, time.Now(), lastStep)
+		if !ok {
+			return false, nil
+		}
+
+		// Only one of two requests racing with the same code may move the step forward
+		result, err := db.DB.Exec(
+			"UPDATE clothing_users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?",
+			step, userID, step,
+		)
+		if err != nil {
+			return false, err
+		}
+		affected, _ := result.RowsAffected()
+		return affected == 1, nil
+	}
+
+	result, err := db.DB.Exec(
+		`UPDATE clothing_user_recovery_codes SET recovery_code_used_at = ?
+         WHERE id_clothing_users = ? AND recovery_code_hash = ? AND recovery_code_used_at IS NULL`,
+		time.Now(), userID, utils.HashToken(utils.NormalizeRecoveryCode(code)),
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected == 1, nil
+}
+
+// checkCurrentPin reports whether pin is the current PIN of the user, the TOTP settings
+// are only changed after the user proves it is them and not someone at an unlocked screen
+func checkCurrentPin(userID int, pin string) bool {
+	var user models.ClothingUser
+	var legacyPin int
+	err := db.DB.QueryRow("SELECT id, pin, pin_hash FROM clothing_users WHERE id = ?", userID).Scan(&user.ID, &legacyPin, &user.PinHash)
+	return err == nil && verifyUserPin(&user, legacyPin, pin)
+}
+
+// replaceRecoveryCodes drops the user's recovery codes and returns a fresh set
+func replaceRecoveryCodes(userID int) ([]string, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM clothing_user_recovery_codes WHERE id_clothing_users = ?", userID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(
+			"INSERT INTO clothing_user_recovery_codes (id_clothing_users, recovery_code_hash, created_at) VALUES (?, ?, ?)",
+			userID, utils.HashToken(code), now,
+		)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, tx.Commit()
+}
+
+// LoginTotp completes the second login step with a TOTP or recovery code
+func LoginTotp(c *gin.Context) {
+	var req models.TotpLoginRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	var challengeID, userID, attempts int
+	var expiresAt time.Time
+	var username string
+	err := db.DB.QueryRow(
+		`SELECT ch.id, ch.id_clothing_users, ch.challenge_attempts, ch.challenge_expires_at, u.username
+         FROM clothing_login_challenges ch JOIN clothing_users u ON u.id = ch.id_clothing_users
+         WHERE ch.challenge_token_hash = ? AND u.user_status = ?`,
+		utils.HashToken(req.ChallengeToken), utils.CLOTHES_USER_STATUS_ACTIVE,
+	).Scan(&challengeID, &userID, &attempts, &expiresAt, &username)
+	if err != nil || time.Now().After(expiresAt) || attempts >= loginChallengeMaxAttempts {
+		if err == nil {
+			_, _ = db.DB.Exec("DELETE FROM clothing_login_challenges WHERE id = ?", challengeID)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errChallengeInvalid.Error(), "restart_login": true})
+		return
+	}
+
+	ok, err := verifyTotpOrRecoveryCode(userID, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+		return
+	}
+	if !ok {
+		_, _ = db.DB.Exec(
+			"UPDATE clothing_login_challenges SET challenge_attempts = challenge_attempts + 1 WHERE id = ?",
+			challengeID,
+		)
+		recordLoginFailure(username, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	if _, err := db.DB.Exec("DELETE FROM clothing_login_challenges WHERE id = ?", challengeID); err != nil {
+		log.Printf("Error removing login challenge: %v", err)
+	}
+	clearLoginAttempts(username, c.ClientIP())
+
+	var mustChangePin bool
+	_ = db.DB.QueryRow("SELECT must_change_pin FROM clothing_users WHERE id = ?", userID).Scan(&mustChangePin)
+
+	token, err := createSession(c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+		return
+	}
+	setSessionCookie(c, token, int(sessionMaxAge().Seconds()))
+
+	c.JSON(http.StatusOK, LoginResponse{
+		Success:       true,
+		Message:       "Login successful",
+		UserID:        userID,
+		MustChangePin: mustChangePin,
+	})
+}
+
+// EnrollTotp creates a new pending TOTP secret and returns its provisioning URI and QR code
+func EnrollTotp(c *gin.Context) {
+	var req models.TotpEnrollRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "PIN must be exactly 6 digits"})
+		return
+	}
+
+	userID := c.GetInt("user_id")
+
+	if !checkCurrentPin(userID, req.Pin) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "PIN is incorrect"})
+		return
+	}
+
+	var username string
+	var role int
+	var enabled bool
+	err := db.DB.QueryRow(
+		"SELECT username, user_role, totp_enabled FROM clothing_users WHERE id = ?", userID,
+	).Scan(&username, &role, &enabled)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !totpAllowedRole(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is only available for owner and manager accounts"})
+		return
+	}
+	if enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := utils.GenerateTotpSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = db.DB.Exec(
+		"UPDATE clothing_users SET totp_secret = ?, totp_last_step = 0, updated_at = ? WHERE id = ?",
+		secret, time.Now(), userID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	uri := utils.TotpProvisioningURI(totpIssuer(), username, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, totpQRSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": uri,
+		"qr_code":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+// ConfirmTotp enables TOTP once the user proves their app generates valid codes, and returns the recovery codes
+func ConfirmTotp(c *gin.Context) {
+	var req models.TotpConfirmRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "PIN and code must be exactly 6 digits"})
+		return
+	}
+
+	userID := c.GetInt("user_id")
+
+	if !checkCurrentPin(userID, req.Pin) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "PIN is incorrect"})
+		return
+	}
+
+	var secret string
+	var enabled bool
+	err := db.DB.QueryRow(
+		"SELECT totp_secret, totp_enabled FROM clothing_users WHERE id = ?", userID,
+	).Scan(&secret, &enabled)
+	if err != nil || secret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start the enrollment first"})
+		return
+	}
+	if enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	step, ok := utils.VerifyTotp(secret, req.Code, time.Now(), 0)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = db.DB.Exec(
+		"UPDATE clothing_users SET totp_enabled = 1, totp_last_step = ?, updated_at = ? WHERE id = ?",
+		step, time.Now(), userID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after checking a current TOTP or recovery code
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req models.TotpCodeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Enter an authentication or recovery code"})
+		return
+	}
+
+	userID := c.GetInt("user_id")
+
+	ok, err := verifyTotpOrRecoveryCode(userID, req.Code)
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// disableTotp clears the TOTP secret and recovery codes of a user
+func disableTotp(userID int) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"UPDATE clothing_users SET totp_secret = '', totp_enabled = 0, totp_last_step = 0, updated_at = ? WHERE id = ?",
+		time.Now(), userID,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM clothing_user_recovery_codes WHERE id_clothing_users = ?", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DisableTotp turns off TOTP for the authenticated user after checking both PIN and a TOTP or recovery code
+func DisableTotp(c *gin.Context) {
+	var req models.TotpDisableRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "PIN must be exactly 6 digits and a code is required"})
+		return
+	}
+
+	userID := c.GetInt("user_id")
+
+	if !checkCurrentPin(userID, req.Pin) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "PIN is incorrect"})
+		return
+	}
+
+	ok, err := verifyTotpOrRecoveryCode(userID, req.Code)
+	if err != nil || !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	if err := disableTotp(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// ResetUserTotp lets a user manager turn off TOTP for someone who lost both their device and recovery codes
+func ResetUserTotp(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var exists int
+	if err := db.DB.QueryRow("SELECT COUNT(*) FROM clothing_users WHERE id = ?", id).Scan(&exists); err != nil || exists == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := disableTotp(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset successfully"})
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"clothingretail/db"
+	"clothingretail/models"
+	"clothingretail/utils"
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const userSelectColumns = `id, username, user_status, user_role, must_change_pin, totp_enabled, created_at, updated_at`
+
+func scanUser(row interface{ Scan(...interface{}) error }, user *models.ClothingUser) error {
+	return row.Scan(&user.ID, &user.Username, &user.UserStatus, &user.UserRole, &user.MustChangePin, &user.TotpEnabled,
+		&user.CreatedAt, &user.UpdatedAt)
+}
+
+// usernameTaken reports whether another user already uses the username, ignoring case
+func usernameTaken(username string, excludeID int) (bool, error) {
+	var count int
+	err := db.DB.QueryRow(
+		"SELECT COUNT(*) FROM clothing_users WHERE username = ? COLLATE NOCASE AND id != ?",
+		username, excludeID,
+	).Scan(&count)
+	return count > 0, err
+}
+
+// isLastActiveOwner reports whether the user is the only active owner left
+func isLastActiveOwner(userID int) (bool, error) {
+	var role, status, owners int
+	err := db.DB.QueryRow("SELECT user_role, user_status FROM clothing_users WHERE id = ?", userID).Scan(&role, &status)
+	if err != nil {
+		return false, err
+	}
+	if role != utils.USER_ROLE_OWNER || status != utils.CLOTHES_USER_STATUS_ACTIVE {
+		return false, nil
+	}
+
+	err = db.DB.QueryRow(
+		"SELECT COUNT(*) FROM clothing_users WHERE user_role = ? AND user_status = ?",
+		utils.USER_ROLE_OWNER, utils.CLOTHES_USER_STATUS_ACTIVE,
+	).Scan(&owners)
+	return owners <= 1, err
+}
+
+// CreateUser handles creating a new staff user
+func CreateUser(c *gin.Context) {
+	var req models.CreateUserRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username is required"})
+		return
+	}
+
+	taken, err := usernameTaken(req.Username, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+		return
+	}
+
+	pinHash, err := utils.HashPin(req.Pin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The PIN was chosen by an administrator, so the new user has to replace it on first login
+	user := models.ClothingUser{
+		Username:      req.Username,
+		UserStatus:    utils.CLOTHES_USER_STATUS_ACTIVE,
+		UserRole:      req.UserRole,
+		MustChangePin: true,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	result, err := db.DB.Exec(
+		`INSERT INTO clothing_users (username, pin, pin_hash, user_status, user_role, must_change_pin, totp_enabled, created_at, updated_at)
+         VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		user.Username, 0, pinHash, user.UserStatus, user.UserRole, user.MustChangePin, user.TotpEnabled,
+		user.CreatedAt, user.UpdatedAt,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	user.ID = int(id)
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// GetUsers retrieves all staff users, optionally filtered by status
+func GetUsers(c *gin.Context) {
+	status := c.Query("status")
+
+	query := "SELECT " + userSelectColumns + " FROM clothing_users WHERE 1=1"
+	var args []interface{}
+
+	if status != "" {
+		query += " AND user_status = ?"
+		args = append(args, status)
+	}
+
+	query += " ORDER BY username"
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	users := []models.ClothingUser{}
+	for rows.Next() {
+		var user models.ClothingUser
+		if err := scanUser(rows, &user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		users = append(users, user)
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// GetUserByID retrieves a single staff user by ID
+func GetUserByID(c *gin.Context) {
+	id := c.Param("id")
+	var user models.ClothingUser
+
+	err := scanUser(db.DB.QueryRow("SELECT "+userSelectColumns+" FROM clothing_users WHERE id = ?", id), &user)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateUser updates the username and role of a staff user
+func UpdateUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username is required"})
+		return
+	}
+
+	taken, err := usernameTaken(req.Username, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+		return
+	}
+
+	if req.UserRole != utils.USER_ROLE_OWNER {
+		lastOwner, err := isLastActiveOwner(id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if lastOwner {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot change the role of the last active owner"})
+			return
+		}
+	}
+
+	result, err := db.DB.Exec(
+		"UPDATE clothing_users SET username = ?, user_role = ?, updated_at = ? WHERE id = ?",
+		req.Username, req.UserRole, time.Now(), id,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
+}
+
+// setUserStatus moves a user to a new status, refusing to lock out the caller or the last owner
+func setUserStatus(c *gin.Context, status int, message string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if status != utils.CLOTHES_USER_STATUS_ACTIVE {
+		if id == c.GetInt("user_id") {
+			c.JSON(http.StatusConflict, gin.H{"error": "You cannot deactivate or suspend your own account"})
+			return
+		}
+
+		lastOwner, err := isLastActiveOwner(id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if lastOwner {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot deactivate or suspend the last active owner"})
+			return
+		}
+	}
+
+	result, err := db.DB.Exec(
+		"UPDATE clothing_users SET user_status = ?, updated_at = ? WHERE id = ?",
+		status, time.Now(), id,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// A deactivated or suspended user is signed out of every device right away
+	if status != utils.CLOTHES_USER_STATUS_ACTIVE {
+		if _, err := revokeUserSessions(id, 0); err != nil {
+			log.Printf("Error revoking sessions of user %d: %v", id, err)
+		}
+	}
+
+	// A manual reactivation also lifts any login lockout on the username
+	if status == utils.CLOTHES_USER_STATUS_ACTIVE {
+		_, err = db.DB.Exec(
+			`DELETE FROM clothing_login_attempts WHERE attempt_key_type = ?
+             AND attempt_key = (SELECT lower(username) FROM clothing_users WHERE id = ?)`,
+			utils.LOGIN_ATTEMPT_KEY_USERNAME, id,
+		)
+		if err != nil {
+			log.Printf("Error clearing login attempts for user %d: %v", id, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     message,
+		"user_status": utils.ClothesUserStatusTrans(status),
+	})
+}
+
+// DeleteUser soft deletes a staff user
+func DeleteUser(c *gin.Context) {
+	setUserStatus(c, utils.CLOTHES_USER_STATUS_INACTIVE, "User deactivated successfully")
+}
+
+// SuspendUser temporarily blocks a staff user from logging in
+func SuspendUser(c *gin.Context) {
+	setUserStatus(c, utils.CLOTHES_USER_STATUS_SUSPENDED, "User suspended successfully")
+}
+
+// ActivateUser reactivates a deactivated or suspended staff user
+func ActivateUser(c *gin.Context) {
+	setUserStatus(c, utils.CLOTHES_USER_STATUS_ACTIVE, "User activated successfully")
+}
+
+// ResetUserPin sets a new PIN for a staff user
+func ResetUserPin(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.ResetPinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var exists int
+	if err := db.DB.QueryRow("SELECT COUNT(*) FROM clothing_users WHERE id = ?", id).Scan(&exists); err != nil || exists == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := setUserPin(id, req.Pin); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The administrator knows the new PIN, so the user has to replace it on next login
+	if err := setMustChangePin(id, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "PIN reset successfully"})
+}
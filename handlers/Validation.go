@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// minPhoneDigits is the least amount of digits a phone number needs, the forms check the same
+const minPhoneDigits = 8
+
+// fieldErrors maps the JSON name of a field to the message the forms show next to its input
+type fieldErrors map[string]string
+
+var (
+	registerValidatorsOnce sync.Once
+	registerValidatorsErr  error
+)
+
+// RegisterValidators adds the custom binding tags and makes validation errors use the JSON field names.
+// Only the first call registers them, bindJSON calls it as well so no binding runs without the tags.
+func RegisterValidators() error {
+	registerValidatorsOnce.Do(func() {
+		registerValidatorsErr = registerValidators()
+	})
+	return registerValidatorsErr
+}
+
+func registerValidators() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("unexpected validator engine")
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	if err := v.RegisterValidation("notblank", validateNotBlank); err != nil {
+		return err
+	}
+	return v.RegisterValidation("phone", validatePhone)
+}
+
+// validateNotBlank refuses strings made only of whitespace
+func validateNotBlank(fl validator.FieldLevel) bool {
+	return strings.TrimSpace(fl.Field().String()) != ""
+}
+
+// validatePhone accepts digits, spaces, dashes, a plus and parentheses with at least minPhoneDigits digits
+func validatePhone(fl validator.FieldLevel) bool {
+	digits := 0
+	for _, r := range fl.Field().String() {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+		case strings.ContainsRune(" -+()", r):
+		default:
+			return false
+		}
+	}
+	return digits >= minPhoneDigits
+}
+
+// validationMessage describes a failed binding tag
+func validationMessage(fe validator.FieldError) string {
+	unit := ""
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Map:
+		unit = " items"
+	}
+
+	switch fe.Tag() {
+	case "required", "notblank":
+		return "is required"
+	case "max":
+		return fmt.Sprintf("must be at most %s%s", fe.Param(), unit)
+	case "min":
+		return fmt.Sprintf("must be at least %s%s", fe.Param(), unit)
+	case "len":
+		return fmt.Sprintf("must be exactly %s%s", fe.Param(), unit)
+	case "email":
+		return "must be a valid email address"
+	case "phone":
+		return "must be a valid phone number"
+	case "numeric":
+		return "must be a number"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	}
+	return "is invalid"
+}
+
+// respondFieldErrors answers with the failing fields, error carries them in one line for clients that only show that
+func respondFieldErrors(c *gin.Context, status int, fields fieldErrors) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	messages := make([]string, 0, len(names))
+	for _, name := range names {
+		messages = append(messages, name+" "+fields[name])
+	}
+
+	c.JSON(status, gin.H{"error": strings.Join(messages, "; "), "fields": fields})
+}
+
+// isUniqueViolation tells whether err comes from a unique index, which backs up a name check that a concurrent
+// request got past
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// bindJSON binds the request body into obj. When it does not validate the failing fields are
+// reported and false is returned.
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	if err := RegisterValidators(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	fields := fieldErrors{}
+	for _, fe := range validationErrors {
+		// The namespace starts with the struct name, nested fields keep their path such as values[0].attribute_value_code
+		name := fe.Namespace()
+		if i := strings.Index(name, "."); i >= 0 {
+			name = name[i+1:]
+		}
+		if _, ok := fields[name]; !ok {
+			fields[name] = validationMessage(fe)
+		}
+	}
+	respondFieldErrors(c, http.StatusBadRequest, fields)
+	return false
+}
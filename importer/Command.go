@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RunCommand runs the import command line: import [-commit] <file.csv|file.xlsx>.
+// Without -commit the file is only checked. It prints the report and returns the exit code.
+func RunCommand(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	commit := flags.Bool("commit", false, "write the import, without it the file is only checked")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s import [-commit] <file.csv|file.xlsx>\n", filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	name := flags.Arg(0)
+	file, err := os.Open(name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer file.Close()
+
+	rows, err := ReadRows(name, file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+
+	result, err := Run(rows, *commit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		return 1
+	}
+
+	for _, rowErr := range result.Errors {
+		fmt.Printf("%s:%d: %s %s\n", name, rowErr.Line, rowErr.Field, rowErr.Message)
+	}
+
+	summary := fmt.Sprintf("%d categories, %d subcategories and %d sizes with an opening quantity of %d",
+		result.Categories, result.Subcategories, result.Sizes, result.Quantity)
+	switch {
+	case len(result.Errors) > 0:
+		fmt.Printf("%d rows, %d errors, nothing imported\n", result.Rows, len(result.Errors))
+		return 1
+	case result.Committed:
+		fmt.Printf("Imported %d rows, created %s\n", result.Rows, summary)
+	default:
+		fmt.Printf("Checked %d rows, the import creates %s. Run again with -commit to import.\n", result.Rows, summary)
+	}
+	return 0
+}
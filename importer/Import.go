@@ -0,0 +1,386 @@
+package importer
+
+import (
+	"clothingretail/db"
+	"clothingretail/models"
+	"clothingretail/utils"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Length limits of the schema, see db/migrate-sqlite/000001_init_category.up.sql
+const (
+	maxCategoryNameLength    = 32
+	maxSubcategoryNameLength = 32
+	maxLocationLength        = 64
+	maxSizeNameLength        = 8
+)
+
+// RowError is a problem with one field of a row
+type RowError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Created is a row the import added, kept for the audit log
+type Created struct {
+	Entity string
+	ID     int64
+	Record interface{}
+}
+
+// Result reports what an import did, or would have done when it was a dry run or had errors.
+// Nothing is written unless Committed is true.
+type Result struct {
+	Committed     bool       `json:"committed"`
+	Rows          int        `json:"rows"`
+	Categories    int        `json:"categories_created"`
+	Subcategories int        `json:"subcategories_created"`
+	Sizes         int        `json:"sizes_created"`
+	Quantity      int        `json:"opening_quantity"`
+	Errors        []RowError `json:"errors"`
+	Created       []Created  `json:"-"`
+}
+
+// run holds the lookups of one import, keyed by lowercased names so a file may spell a name differently
+type run struct {
+	tx         *sql.Tx
+	now        time.Time
+	result     *Result
+	categories map[string]int
+	subs       map[string]*subcategory
+	sizes      map[string]bool
+	locations  map[string]*location
+}
+
+type subcategory struct {
+	id       int
+	location *location
+}
+
+type location struct {
+	id   int
+	name string
+}
+
+func key(parts ...interface{}) string {
+	return strings.ToLower(fmt.Sprint(parts...))
+}
+
+// Run imports rows in a single transaction. Existing active categories, subcategories and sizes are matched
+// by name without case and reused, the rest is created. A quantity books an opening BUY movement for the size.
+// The transaction is only committed when commit is true and no row has an error, so a dry run goes through
+// exactly the same checks as the real import.
+func Run(rows []Row, commit bool) (*Result, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	r := &run{
+		tx:         tx,
+		now:        time.Now(),
+		result:     &Result{Rows: len(rows), Errors: []RowError{}},
+		categories: map[string]int{},
+		subs:       map[string]*subcategory{},
+		sizes:      map[string]bool{},
+		locations:  map[string]*location{},
+	}
+
+	for _, row := range rows {
+		if err := r.importRow(row); err != nil {
+			return nil, fmt.Errorf("line %d: %w", row.Line, err)
+		}
+	}
+
+	if !commit || len(r.result.Errors) > 0 {
+		r.result.Created = nil
+		return r.result, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	r.result.Committed = true
+	return r.result, nil
+}
+
+func (r *run) fail(row Row, field, message string) {
+	r.result.Errors = append(r.result.Errors, RowError{Line: row.Line, Field: field, Message: message})
+}
+
+// checkRow reports the fields of a row that cannot be imported whatever is in the database
+func (r *run) checkRow(row Row) (int, bool) {
+	ok := true
+	check := func(field, value string, maxLength int, required bool) {
+		if value == "" {
+			if required {
+				r.fail(row, field, "is required")
+				ok = false
+			}
+			return
+		}
+		if utf8.RuneCountInString(value) > maxLength {
+			r.fail(row, field, fmt.Sprintf("must be at most %d characters", maxLength))
+			ok = false
+		}
+	}
+	check(ColumnCategory, row.Category, maxCategoryNameLength, true)
+	check(ColumnSubcategory, row.Subcategory, maxSubcategoryNameLength, true)
+	check(ColumnLocation, row.Location, maxLocationLength, false)
+	check(ColumnSize, row.Size, maxSizeNameLength, false)
+
+	quantity := 0
+	if row.Quantity != "" {
+		// Spreadsheets may hand whole numbers over as 12.0
+		parsed, err := strconv.ParseFloat(row.Quantity, 64)
+		if err != nil || parsed < 0 || parsed != math.Trunc(parsed) || parsed > math.MaxInt32 {
+			r.fail(row, ColumnQuantity, "must be a whole number of at least 0")
+			return 0, false
+		}
+		quantity = int(parsed)
+	}
+	if quantity > 0 && row.Size == "" {
+		r.fail(row, ColumnSize, "is required for an opening quantity")
+		ok = false
+	}
+	return quantity, ok
+}
+
+// importRow adds what a row needs. Row problems are collected in the result, the error is for database failures.
+func (r *run) importRow(row Row) error {
+	quantity, ok := r.checkRow(row)
+	if !ok {
+		return nil
+	}
+
+	categoryID, err := r.category(row)
+	if err != nil {
+		return err
+	}
+
+	sub, err := r.subcategory(row, categoryID)
+	if err != nil || sub == nil {
+		return err
+	}
+
+	if row.Size == "" {
+		return nil
+	}
+	sizeKey := key(sub.id, "|", row.Size)
+	if r.sizes[sizeKey] {
+		r.fail(row, ColumnSize, "is listed more than once for this subcategory")
+		return nil
+	}
+	r.sizes[sizeKey] = true
+
+	sizeID, created, err := r.size(row, sub.id)
+	if err != nil {
+		return err
+	}
+	if quantity == 0 {
+		return nil
+	}
+
+	if !created {
+		var movements int
+		err := r.tx.QueryRow(
+			"SELECT COUNT(*) FROM clothing_inventory_movement WHERE id_clothing_size = ?", sizeID,
+		).Scan(&movements)
+		if err != nil {
+			return err
+		}
+		if movements > 0 {
+			r.fail(row, ColumnQuantity, "size already has stock, an opening quantity is only taken for new stock")
+			return nil
+		}
+	}
+
+	_, err = r.tx.Exec(
+		`INSERT INTO clothing_inventory_movement (id_clothing_category, id_clothing_size, id_clothing_location,
+         clothes_movement_action, clothes_qty_in, clothes_qty_out, clothes_qty_total,
+         clothes_cat_status_sub, created_at, updated_at) VALUES (?, ?, ?, ?, ?, 0, ?, 1, ?, ?)`,
+		sub.id, sizeID, sub.location.id, utils.CLOTHES_MOV_ACTION_BUY, quantity, quantity, r.now, r.now,
+	)
+	if err != nil {
+		return err
+	}
+	r.result.Quantity += quantity
+	return nil
+}
+
+// category finds the active category of the row or creates it
+func (r *run) category(row Row) (int, error) {
+	categoryKey := key(row.Category)
+	if id, ok := r.categories[categoryKey]; ok {
+		return id, nil
+	}
+
+	var id int
+	err := r.tx.QueryRow(
+		`SELECT id FROM clothing_category WHERE clothes_cat_name = ? COLLATE NOCASE AND clothes_cat_status = ?
+         ORDER BY id LIMIT 1`,
+		row.Category, utils.CAT_STATUS_ACTIVE,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		category := models.ClothingCategory{
+			ClothesCatName:   row.Category,
+			ClothesCatStatus: utils.CAT_STATUS_ACTIVE,
+			CreatedAt:        r.now,
+			UpdatedAt:        r.now,
+		}
+		result, err := r.tx.Exec(
+			"INSERT INTO clothing_category (clothes_cat_name, clothes_notes, clothes_cat_status, created_at, updated_at) VALUES (?, '', ?, ?, ?)",
+			category.ClothesCatName, category.ClothesCatStatus, category.CreatedAt, category.UpdatedAt,
+		)
+		if err != nil {
+			return 0, err
+		}
+		newID, _ := result.LastInsertId()
+		category.ID = int(newID)
+		id = category.ID
+		r.result.Categories++
+		r.result.Created = append(r.result.Created, Created{utils.AUDIT_ENTITY_CATEGORY, newID, category})
+	} else if err != nil {
+		return 0, err
+	}
+
+	r.categories[categoryKey] = id
+	return id, nil
+}
+
+// location finds the active location a row names, nil when there is none
+func (r *run) location(name string) (*location, error) {
+	locationKey := key(name)
+	if loc, ok := r.locations[locationKey]; ok {
+		return loc, nil
+	}
+
+	loc := &location{}
+	err := r.tx.QueryRow(
+		"SELECT id, location_name FROM clothing_location WHERE location_name = ? COLLATE NOCASE AND location_status = ?",
+		name, utils.LOCATION_STATUS_ACTIVE,
+	).Scan(&loc.id, &loc.name)
+	if err == sql.ErrNoRows {
+		loc = nil
+	} else if err != nil {
+		return nil, err
+	}
+	r.locations[locationKey] = loc
+	return loc, nil
+}
+
+// subcategory finds the active subcategory of the row within its category or creates it. A new subcategory needs
+// an active location, an existing one keeps its location and a row naming a different one is refused.
+// It returns nil when the row has an error.
+func (r *run) subcategory(row Row, categoryID int) (*subcategory, error) {
+	var rowLocation *location
+	if row.Location != "" {
+		var err error
+		rowLocation, err = r.location(row.Location)
+		if err != nil {
+			return nil, err
+		}
+		if rowLocation == nil {
+			r.fail(row, ColumnLocation, "is not an active location")
+			return nil, nil
+		}
+	}
+
+	subKey := key(categoryID, "|", row.Subcategory)
+	sub, ok := r.subs[subKey]
+	if !ok {
+		sub = &subcategory{location: &location{}}
+		err := r.tx.QueryRow(
+			`SELECT id, COALESCE(id_clothing_location, 0), clothes_cat_location_sub FROM clothing_category_sub
+             WHERE id_clothing_category = ? AND clothes_cat_name_sub = ? COLLATE NOCASE AND clothes_cat_status_sub = ?
+             ORDER BY id LIMIT 1`,
+			categoryID, row.Subcategory, utils.CAT_SUB_STATUS_ACTIVE,
+		).Scan(&sub.id, &sub.location.id, &sub.location.name)
+		if err == sql.ErrNoRows {
+			if rowLocation == nil {
+				r.fail(row, ColumnLocation, "is required for a new subcategory")
+				return nil, nil
+			}
+
+			categorySub := models.ClothingCategorySub{
+				IDClothingCategory:    categoryID,
+				ClothesCatNameSub:     row.Subcategory,
+				ClothesCatLocationSub: rowLocation.name,
+				IDClothingLocation:    rowLocation.id,
+				ClothesCatStatusSub:   utils.CAT_SUB_STATUS_ACTIVE,
+				Attributes:            map[string][]string{},
+				CreatedAt:             r.now,
+				UpdatedAt:             r.now,
+			}
+			result, err := r.tx.Exec(
+				`INSERT INTO clothing_category_sub (id_clothing_category, clothes_cat_name_sub, clothes_cat_location_sub,
+                 id_clothing_location, clothes_cat_status_sub, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				categorySub.IDClothingCategory, categorySub.ClothesCatNameSub, categorySub.ClothesCatLocationSub,
+				categorySub.IDClothingLocation, categorySub.ClothesCatStatusSub, categorySub.CreatedAt, categorySub.UpdatedAt,
+			)
+			if err != nil {
+				return nil, err
+			}
+			newID, _ := result.LastInsertId()
+			categorySub.ID = int(newID)
+			sub = &subcategory{id: categorySub.ID, location: rowLocation}
+			r.result.Subcategories++
+			r.result.Created = append(r.result.Created, Created{utils.AUDIT_ENTITY_CATEGORY_SUB, newID, categorySub})
+		} else if err != nil {
+			return nil, err
+		}
+		r.subs[subKey] = sub
+	}
+
+	if rowLocation != nil && rowLocation.id != sub.location.id {
+		r.fail(row, ColumnLocation, fmt.Sprintf("differs from the location %q of the subcategory", sub.location.name))
+		return nil, nil
+	}
+	return sub, nil
+}
+
+// size finds the active size of the row within its subcategory or creates it, created tells which one happened
+func (r *run) size(row Row, subID int) (int, bool, error) {
+	var id int
+	err := r.tx.QueryRow(
+		`SELECT id FROM clothing_size WHERE id_clothing_category_sub = ? AND clothes_size_name = ? COLLATE NOCASE
+         AND clothes_size_status = ? ORDER BY id LIMIT 1`,
+		subID, row.Size, utils.CLOTHES_SIZE_STATUS_ACTIVE,
+	).Scan(&id)
+	if err == nil {
+		return id, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, false, err
+	}
+
+	size := models.ClothingSize{
+		IDClothingCategorySub: subID,
+		ClothesSizeName:       row.Size,
+		ClothesSizeStatus:     utils.CLOTHES_SIZE_STATUS_ACTIVE,
+		Measurements:          []models.ClothingSizeMeasurement{},
+		CreatedAt:             r.now,
+		UpdatedAt:             r.now,
+	}
+	result, err := r.tx.Exec(
+		`INSERT INTO clothing_size (id_clothing_category_sub, clothes_size_name, clothes_size_notes,
+         clothes_size_status, created_at, updated_at) VALUES (?, ?, '', ?, ?, ?)`,
+		size.IDClothingCategorySub, size.ClothesSizeName, size.ClothesSizeStatus, size.CreatedAt, size.UpdatedAt,
+	)
+	if err != nil {
+		return 0, false, err
+	}
+	newID, _ := result.LastInsertId()
+	size.ID = int(newID)
+	r.result.Sizes++
+	r.result.Created = append(r.result.Created, Created{utils.AUDIT_ENTITY_SIZE, newID, size})
+	return size.ID, true, nil
+}
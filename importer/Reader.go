@@ -0,0 +1,179 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// MaxRows is the largest amount of data rows a single import takes
+const MaxRows = 10000
+
+var ErrUnsupportedFormat = errors.New("import file must be a .csv or .xlsx file")
+
+// Column names the header row may use, matched without case with spaces and dashes read as underscores.
+// The schema column names are accepted as well.
+var columnAliases = map[string]string{
+	"category":                 ColumnCategory,
+	"clothes_cat_name":         ColumnCategory,
+	"subcategory":              ColumnSubcategory,
+	"sub_category":             ColumnSubcategory,
+	"clothes_cat_name_sub":     ColumnSubcategory,
+	"location":                 ColumnLocation,
+	"clothes_cat_location_sub": ColumnLocation,
+	"location_name":            ColumnLocation,
+	"size":                     ColumnSize,
+	"clothes_size_name":        ColumnSize,
+	"quantity":                 ColumnQuantity,
+	"qty":                      ColumnQuantity,
+	"opening_quantity":         ColumnQuantity,
+}
+
+const (
+	ColumnCategory    = "category"
+	ColumnSubcategory = "subcategory"
+	ColumnLocation    = "location"
+	ColumnSize        = "size"
+	ColumnQuantity    = "quantity"
+)
+
+// Row is one data row of the spreadsheet, Line is its line in the file for the error report
+type Row struct {
+	Line        int    `json:"line"`
+	Category    string `json:"category"`
+	Subcategory string `json:"subcategory"`
+	Location    string `json:"location"`
+	Size        string `json:"size"`
+	Quantity    string `json:"quantity"`
+}
+
+// ReadRows reads the rows of a CSV or XLSX file, the format is taken from the file name.
+// The first row names the columns, category, subcategory and size are required. Empty rows are skipped.
+func ReadRows(name string, r io.Reader) ([]Row, error) {
+	var records [][]string
+	var lines []int
+	var err error
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		records, lines, err = readCSV(r)
+	case ".xlsx":
+		records, lines, err = readXLSX(r)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("import file is empty")
+	}
+
+	columns := map[string]int{}
+	for i, header := range records[0] {
+		key := strings.ToLower(strings.TrimSpace(header))
+		key = strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+		if column, ok := columnAliases[key]; ok {
+			if _, dup := columns[column]; dup {
+				return nil, fmt.Errorf("column %s appears twice", column)
+			}
+			columns[column] = i
+		}
+	}
+	for _, column := range []string{ColumnCategory, ColumnSubcategory, ColumnSize} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("column %s is missing", column)
+		}
+	}
+
+	cell := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []Row
+	for i, record := range records[1:] {
+		row := Row{
+			Line:        lines[i+1],
+			Category:    cell(record, ColumnCategory),
+			Subcategory: cell(record, ColumnSubcategory),
+			Location:    cell(record, ColumnLocation),
+			Size:        cell(record, ColumnSize),
+			Quantity:    cell(record, ColumnQuantity),
+		}
+		if row == (Row{Line: row.Line}) {
+			continue
+		}
+		if len(rows) == MaxRows {
+			return nil, fmt.Errorf("import file has more than %d rows", MaxRows)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// readCSV reads comma separated files, and semicolon separated ones as spreadsheets write them in some locales.
+// Next to the records it returns the line each record starts on, blank lines are skipped by the reader.
+func readCSV(r io.Reader) ([][]string, []int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	var records [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	return records, lines, nil
+}
+
+// readXLSX reads the first sheet of a workbook, the lines are the row numbers
+func readXLSX(r io.Reader) ([][]string, []int, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, nil, errors.New("workbook has no sheets")
+	}
+	records, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lines := make([]int, len(records))
+	for i := range records {
+		lines[i] = i + 1
+	}
+	return records, lines, nil
+}
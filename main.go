@@ -4,7 +4,11 @@ import (
 	"clothingretail/conf"
 	"clothingretail/db"
 	"clothingretail/handlers"
+	"clothingretail/importer"
+	"clothingretail/storage"
+	"clothingretail/utils"
 	"log"
+	"os"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,6 +21,37 @@ func main() {
 	}
 	defer db.CloseDB()
 
+	// Run the catalog import instead of the server when started as: import [-commit] <file>
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		code := importer.RunCommand(os.Args[2:])
+		db.CloseDB()
+		os.Exit(code)
+	}
+
+	// Hash any PIN still stored in plaintext
+	if err := handlers.MigrateUserPins(); err != nil {
+		log.Fatal("Failed to migrate user PINs:", err)
+	}
+
+	// Select where uploaded pictures and documents are kept
+	if err := storage.InitStorage(
+		conf.Koan.String(conf.RunMode+".storage_backend"),
+		conf.Koan.String("filestore"),
+		conf.Koan.String(conf.RunMode+".seaweed_url"),
+	); err != nil {
+		log.Fatal("Failed to initialize storage:", err)
+	}
+
+	// Move base64 pictures still stored in the database to the storage backend
+	if err := handlers.MigrateInlinePictures(); err != nil {
+		log.Fatal("Failed to migrate pictures:", err)
+	}
+
+	// Register the custom binding tags used by the request models
+	if err := handlers.RegisterValidators(); err != nil {
+		log.Fatal("Failed to register validators:", err)
+	}
+
 	// Create default user if none exists
 	if err := handlers.CreateDefaultUser(); err != nil {
 		log.Println("Warning: Failed to create default user:", err)
@@ -25,6 +60,15 @@ func main() {
 	// Initialize Gin router
 	router := gin.Default()
 
+	// Only take the client IP from forwarding headers sent by a configured proxy
+	trustedProxies := conf.Koan.Strings(conf.RunMode + ".trusted_proxies")
+	if len(trustedProxies) == 0 {
+		trustedProxies = nil
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal("Failed to set trusted proxies:", err)
+	}
+
 	// Serve static files (CSS, JS)
 	router.Static("/static", "./templates")
 
@@ -37,11 +81,12 @@ func main() {
 	auth := router.Group("/api/auth")
 	{
 		auth.POST("/login", handlers.Login)
+		auth.POST("/login/totp", handlers.LoginTotp)
 	}
 
 	// Protected routes - apply middleware
 	protected := router.Group("/")
-	protected.Use(handlers.AuthMiddleware())
+	protected.Use(handlers.AuthMiddleware(), handlers.CsrfMiddleware())
 	{
 		// Serve index page (protected)
 		protected.GET("/", func(c *gin.Context) {
@@ -50,61 +95,163 @@ func main() {
 		})
 
 		// HTML page routes (protected)
-		protected.GET("/create-category", func(c *gin.Context) {
+		protected.GET("/create-category", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), func(c *gin.Context) {
 			log.Println("Creating category")
 			c.File("./templates/create-category.html")
 		})
-		protected.GET("/edit-category", func(c *gin.Context) {
+		protected.GET("/edit-category", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), func(c *gin.Context) {
 			c.File("./templates/edit-category.html")
 		})
-		protected.GET("/create-category-sub", func(c *gin.Context) {
+		protected.GET("/create-category-sub", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), func(c *gin.Context) {
 			c.File("./templates/create-category-sub.html")
 		})
-		protected.GET("/edit-category-sub", func(c *gin.Context) {
+		protected.GET("/edit-category-sub", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), func(c *gin.Context) {
 			c.File("./templates/edit-category-sub.html")
 		})
-		protected.GET("/create-customer", func(c *gin.Context) {
+		protected.GET("/create-customer", handlers.RequirePermission(utils.PERM_CUSTOMER_EDIT), func(c *gin.Context) {
 			c.File("./templates/create-customer.html")
 		})
-		protected.GET("/create-rental", func(c *gin.Context) {
+		protected.GET("/create-rental", handlers.RequirePermission(utils.PERM_RENTAL_EDIT), func(c *gin.Context) {
 			c.File("./templates/create-rental.html")
 		})
-		protected.GET("/return-rental", func(c *gin.Context) {
+		protected.GET("/return-rental", handlers.RequirePermission(utils.PERM_RENTAL_EDIT), func(c *gin.Context) {
 			c.File("./templates/return-rental.html")
 		})
 
+		// Change PIN page, the only page reachable while a PIN change is pending
+		protected.GET("/change-pin", func(c *gin.Context) {
+			c.File("./templates/change-pin.html")
+		})
+
 		// Logout route (protected - must be authenticated to logout)
 		protected.POST("/api/auth/logout", handlers.Logout)
 
+		// Account security routes, never reachable with an api token
+		protected.POST("/api/auth/change-pin", handlers.RequireSession(), handlers.ChangePin)
+		protected.POST("/api/auth/totp/enroll", handlers.RequireSession(), handlers.EnrollTotp)
+		protected.POST("/api/auth/totp/confirm", handlers.RequireSession(), handlers.ConfirmTotp)
+		protected.POST("/api/auth/totp/disable", handlers.RequireSession(), handlers.DisableTotp)
+		protected.POST("/api/auth/totp/recovery-codes", handlers.RequireSession(), handlers.RegenerateRecoveryCodes)
+
 		// API routes (protected)
 		api := protected.Group("/api")
 		{
 			// Category routes
-			api.POST("/categories", handlers.CreateCategory)
-			api.GET("/categories", handlers.GetCategories)
-			api.GET("/categories/:id", handlers.GetCategoryByID)
-			api.PUT("/categories/:id", handlers.UpdateCategory)
-			api.DELETE("/categories/:id", handlers.DeleteCategory)
+			api.POST("/categories", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.CreateCategory)
+			api.GET("/categories", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.GetCategories)
+			api.GET("/categories/:id", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.GetCategoryByID)
+			api.PUT("/categories/:id", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.UpdateCategory)
+			api.DELETE("/categories/:id", handlers.RequirePermission(utils.PERM_CATALOG_DELETE), handlers.DeleteCategory)
 
 			// Subcategory routes
-			api.POST("/categories-sub", handlers.CreateCategorySub)
-			api.GET("/categories-sub", handlers.GetCategoriesSub)
-			api.GET("/categories-sub/:id", handlers.GetCategorySubByID)
-			api.PUT("/categories-sub/:id", handlers.UpdateCategorySub)
-			api.DELETE("/categories-sub/:id", handlers.DeleteCategorySub)
+			api.POST("/categories-sub", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.CreateCategorySub)
+			api.GET("/categories-sub", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.GetCategoriesSub)
+			api.GET("/categories-sub/:id", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.GetCategorySubByID)
+			api.PUT("/categories-sub/:id", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.UpdateCategorySub)
+			api.DELETE("/categories-sub/:id", handlers.RequirePermission(utils.PERM_CATALOG_DELETE), handlers.DeleteCategorySub)
+			api.POST("/categories-sub/:id/pictures/:slot", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.UploadCategorySubPicture)
+			api.GET("/categories-sub/:id/pictures/:slot", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.GetCategorySubPicture)
+			api.DELETE("/categories-sub/:id/pictures/:slot", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.DeleteCategorySubPicture)
+			api.POST("/categories-sub/:id/size-chart", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.ApplySizeChart)
+			api.PUT("/categories-sub/:id/attributes", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.SetCategorySubAttributes)
+
+			// Attribute routes
+			api.GET("/attributes", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.GetAttributes)
+			api.POST("/attributes", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.CreateAttribute)
+			api.GET("/attributes/:id", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.GetAttributeByID)
+			api.PUT("/attributes/:id", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.UpdateAttribute)
+			api.DELETE("/attributes/:id", handlers.RequirePermission(utils.PERM_CATALOG_DELETE), handlers.DeleteAttribute)
 
 			// Customer routes
-			api.POST("/customers", handlers.CreateCustomer)
-			api.GET("/customers", handlers.GetCustomers)
-			api.GET("/customers/:id", handlers.GetCustomerByID)
+			api.POST("/customers", handlers.RequirePermission(utils.PERM_CUSTOMER_EDIT), handlers.CreateCustomer)
+			api.GET("/customers", handlers.RequirePermission(utils.PERM_CUSTOMER_VIEW), handlers.GetCustomers)
+			api.GET("/customers/:id", handlers.RequirePermission(utils.PERM_CUSTOMER_VIEW), handlers.GetCustomerByID)
 
 			// Size routes
-			api.GET("/sizes", handlers.GetSizes)
+			api.GET("/sizes", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.GetSizes)
+			api.POST("/sizes", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.CreateSize)
+			api.GET("/sizes/:id", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.GetSizeByID)
+			api.PUT("/sizes/:id", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.UpdateSize)
+			api.DELETE("/sizes/:id", handlers.RequirePermission(utils.PERM_CATALOG_DELETE), handlers.DeleteSize)
+
+			// Size chart routes
+			api.GET("/size-charts", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.GetSizeCharts)
+			api.POST("/size-charts", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.CreateSizeChart)
+			api.GET("/size-charts/:id", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.GetSizeChartByID)
+			api.PUT("/size-charts/:id", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.UpdateSizeChart)
+			api.DELETE("/size-charts/:id", handlers.RequirePermission(utils.PERM_CATALOG_DELETE), handlers.DeleteSizeChart)
+
+			// Inventory routes, stock levels computed from the inventory movements and open rentals
+			api.GET("/inventory", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.GetInventory)
+
+			// Location routes
+			api.GET("/locations", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.GetLocations)
+			api.POST("/locations", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.CreateLocation)
+			api.POST("/locations/transfer", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.TransferStock)
+			api.GET("/locations/:id", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.GetLocationByID)
+			api.PUT("/locations/:id", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.UpdateLocation)
+			api.DELETE("/locations/:id", handlers.RequirePermission(utils.PERM_CATALOG_DELETE), handlers.DeleteLocation)
 
 			// Rental routes
-			api.POST("/rentals", handlers.RentClothing)
-			api.POST("/rentals/return", handlers.ReturnClothing)
-			api.GET("/rentals", handlers.GetRentals)
+			api.POST("/rentals", handlers.RequirePermission(utils.PERM_RENTAL_EDIT), handlers.RentClothing)
+			api.POST("/rentals/return", handlers.RequirePermission(utils.PERM_RENTAL_EDIT), handlers.ReturnClothing)
+			api.GET("/rentals", handlers.RequirePermission(utils.PERM_RENTAL_VIEW), handlers.GetRentals)
+
+			// Import routes, a dry run unless commit=true
+			api.POST("/import/catalog", handlers.RequirePermission(utils.PERM_CATALOG_EDIT), handlers.ImportCatalog)
+
+			// Export routes, format=csv, xlsx or json with the filters of the list routes
+			api.GET("/export/categories", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.ExportCategories)
+			api.GET("/export/subcategories", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.ExportSubcategories)
+			api.GET("/export/sizes", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.ExportSizes)
+			api.GET("/export/stock", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.ExportStock)
+			api.GET("/export/locations", handlers.RequirePermission(utils.PERM_CATALOG_VIEW), handlers.ExportLocations)
+			api.GET("/export/customers", handlers.RequirePermission(utils.PERM_CUSTOMER_VIEW), handlers.ExportCustomers)
+			api.GET("/export/rentals", handlers.RequirePermission(utils.PERM_RENTAL_VIEW), handlers.ExportRentals)
+
+			// Search routes, every type of result is limited to what the caller may view
+			api.GET("/search", handlers.Search)
+
+			// Personal api token routes
+			api.POST("/tokens", handlers.CreateApiToken)
+			api.GET("/tokens", handlers.GetApiTokens)
+			api.DELETE("/tokens/:id", handlers.RevokeApiToken)
+
+			// Own session routes, used to sign out lost devices
+			api.GET("/sessions", handlers.GetSessions)
+			api.DELETE("/sessions", handlers.RequireSession(), handlers.RevokeOtherSessions)
+			api.DELETE("/sessions/:id", handlers.RequireSession(), handlers.RevokeSession)
+
+			// Audit log routes
+			api.GET("/audit", handlers.RequirePermission(utils.PERM_AUDIT_VIEW), handlers.GetAuditLog)
+
+			// User management routes
+			users := api.Group("/users")
+			users.Use(handlers.RequirePermission(utils.PERM_USER_MANAGE))
+			{
+				users.POST("", handlers.CreateUser)
+				users.GET("", handlers.GetUsers)
+				users.GET("/:id", handlers.GetUserByID)
+				users.PUT("/:id", handlers.UpdateUser)
+				users.DELETE("/:id", handlers.DeleteUser)
+				users.POST("/:id/suspend", handlers.SuspendUser)
+				users.POST("/:id/activate", handlers.ActivateUser)
+				users.POST("/:id/reset-pin", handlers.ResetUserPin)
+				users.DELETE("/:id/totp", handlers.ResetUserTotp)
+				users.GET("/:id/sessions", handlers.GetUserSessions)
+				users.DELETE("/:id/sessions", handlers.RevokeUserSessions)
+				users.DELETE("/:id/sessions/:session_id", handlers.RevokeUserSession)
+				users.GET("/:id/tokens", handlers.GetUserApiTokens)
+				users.POST("/:id/tokens", handlers.CreateUserApiToken)
+			}
+
+			// Login lockout routes
+			lockouts := api.Group("/lockouts")
+			lockouts.Use(handlers.RequirePermission(utils.PERM_USER_MANAGE))
+			{
+				lockouts.GET("", handlers.GetLockouts)
+				lockouts.DELETE("/:id", handlers.ClearLockout)
+			}
 		}
 	}
 
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+type ClothingApiToken struct {
+	ID              int        `json:"id"`
+	IDClothingUsers int        `json:"id_clothing_users"`
+	TokenName       string     `json:"token_name"`
+	TokenPrefix     string     `json:"token_prefix"`
+	TokenScopes     []string   `json:"token_scopes"`
+	TokenStatus     int        `json:"token_status"`
+	TokenExpiresAt  *time.Time `json:"token_expires_at"`
+	TokenLastUsedAt *time.Time `json:"token_last_used_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// Token holds the raw bearer token, it is only filled in the response that creates it
+	Token string `json:"token,omitempty"`
+}
+
+type CreateApiTokenRequest struct {
+	TokenName     string   `json:"token_name" binding:"required,max=64"`
+	TokenScopes   []string `json:"token_scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0"`
+}
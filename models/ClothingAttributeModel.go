@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+)
+
+type ClothingAttribute struct {
+	ID                int                      `json:"id"`
+	AttributeCode     string                   `json:"attribute_code"`
+	AttributeName     string                   `json:"attribute_name"`
+	AttributeMultiple bool                     `json:"attribute_multiple"`
+	AttributeStatus   int                      `json:"attribute_status"`
+	Values            []ClothingAttributeValue `json:"values"`
+	CreatedAt         time.Time                `json:"created_at"`
+	UpdatedAt         time.Time                `json:"updated_at"`
+}
+
+type ClothingAttributeValue struct {
+	ID                   int       `json:"id"`
+	IDClothingAttribute  int       `json:"id_clothing_attribute"`
+	AttributeValueCode   string    `json:"attribute_value_code"`
+	AttributeValueName   string    `json:"attribute_value_name"`
+	AttributeValueOrder  int       `json:"attribute_value_order"`
+	AttributeValueStatus int       `json:"attribute_value_status"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// AttributeRequest creates or updates an attribute. Codes are used in filters and cannot change once created,
+// on update values are matched by code: new codes are added and missing ones are deactivated and
+// removed from the subcategories.
+type AttributeRequest struct {
+	AttributeCode     string                  `json:"attribute_code" binding:"required,max=32"`
+	AttributeName     string                  `json:"attribute_name" binding:"required,max=64"`
+	AttributeMultiple bool                    `json:"attribute_multiple"`
+	Values            []AttributeValueRequest `json:"values" binding:"required,min=1,dive"`
+}
+
+type AttributeValueRequest struct {
+	AttributeValueCode string `json:"attribute_value_code" binding:"required,max=32"`
+	AttributeValueName string `json:"attribute_value_name" binding:"required,max=64"`
+}
+
+// CategorySubAttributesRequest replaces the attributes of a subcategory, keyed by attribute code
+// with the value codes, e.g. {"color": ["red", "gold"], "gender": ["female"]}
+type CategorySubAttributesRequest struct {
+	Attributes map[string][]string `json:"attributes" binding:"required"`
+}
+
+// AttributeFacet counts the subcategories per value of an attribute
+type AttributeFacet struct {
+	AttributeCode string                `json:"attribute_code"`
+	AttributeName string                `json:"attribute_name"`
+	Values        []AttributeFacetValue `json:"values"`
+}
+
+type AttributeFacetValue struct {
+	AttributeValueCode string `json:"attribute_value_code"`
+	AttributeValueName string `json:"attribute_value_name"`
+	Count              int    `json:"count"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ClothingAuditLog struct {
+	ID              int             `json:"id"`
+	IDClothingUsers int             `json:"id_clothing_users"`
+	Username        string          `json:"username"`
+	AuditAction     int             `json:"audit_action"`
+	AuditActionStr  string          `json:"audit_action_str"`
+	AuditEntityType string          `json:"audit_entity_type"`
+	AuditEntityID   int64           `json:"audit_entity_id"`
+	AuditDiff       json.RawMessage `json:"audit_diff"`
+	AuditClientIP   string          `json:"audit_client_ip"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
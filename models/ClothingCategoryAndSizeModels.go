@@ -14,28 +14,120 @@ type ClothingCategory struct {
 }
 
 type ClothingCategorySub struct {
-	ID                    int       `json:"id"`
-	IDClothingCategory    int       `json:"id_clothing_category"`
-	ClothesCatNameSub     string    `json:"clothes_cat_name_sub"`
-	ClothesCatLocationSub string    `json:"clothes_cat_location_sub"`
-	ClothesPicture1       *string   `json:"clothes_picture_1"`
-	ClothesPicture2       *string   `json:"clothes_picture_2"`
-	ClothesPicture3       *string   `json:"clothes_picture_3"`
-	ClothesPicture4       *string   `json:"clothes_picture_4"`
-	ClothesPicture5       *string   `json:"clothes_picture_5"`
-	ClothesCatStatusSub   int       `json:"clothes_cat_status_sub"`
-	CreatedAt             time.Time `json:"created_at"`
-	UpdatedAt             time.Time `json:"updated_at"`
+	ID                    int                 `json:"id"`
+	IDClothingCategory    int                 `json:"id_clothing_category"`
+	ClothesCatNameSub     string              `json:"clothes_cat_name_sub"`
+	ClothesCatLocationSub string              `json:"clothes_cat_location_sub"`
+	IDClothingLocation    int                 `json:"id_clothing_location"`
+	ClothesPicture1       *string             `json:"clothes_picture_1"`
+	ClothesPicture2       *string             `json:"clothes_picture_2"`
+	ClothesPicture3       *string             `json:"clothes_picture_3"`
+	ClothesPicture4       *string             `json:"clothes_picture_4"`
+	ClothesPicture5       *string             `json:"clothes_picture_5"`
+	ClothesCatStatusSub   int                 `json:"clothes_cat_status_sub"`
+	Attributes            map[string][]string `json:"attributes"`
+	CreatedAt             time.Time           `json:"created_at"`
+	UpdatedAt             time.Time           `json:"updated_at"`
+}
+
+// ClothingCategoryRequest creates or updates a category, the name has to be unique among the active categories
+type ClothingCategoryRequest struct {
+	ClothesCatName string `json:"clothes_cat_name" binding:"required,notblank,max=32"`
+	ClothesNotes   string `json:"clothes_notes" binding:"max=256"`
+}
+
+// ClothingCategorySubRequest creates or updates a subcategory, the name has to be unique among the
+// active subcategories of its category. The location is given by id or by the name of an active location.
+type ClothingCategorySubRequest struct {
+	IDClothingCategory    int    `json:"id_clothing_category" binding:"required"`
+	ClothesCatNameSub     string `json:"clothes_cat_name_sub" binding:"required,notblank,max=32"`
+	ClothesCatLocationSub string `json:"clothes_cat_location_sub" binding:"max=64"`
+	IDClothingLocation    int    `json:"id_clothing_location"`
+
+	// Older clients still send pictures inline as base64, new ones upload them through
+	// /api/categories-sub/:id/pictures/:slot. A picture URL as returned by the API leaves its slot unchanged.
+	ClothesPicture1 *string `json:"clothes_picture_1"`
+	ClothesPicture2 *string `json:"clothes_picture_2"`
+	ClothesPicture3 *string `json:"clothes_picture_3"`
+	ClothesPicture4 *string `json:"clothes_picture_4"`
+	ClothesPicture5 *string `json:"clothes_picture_5"`
 }
 
 type ClothingSize struct {
-	ID                    int       `json:"id"`
-	IDClothingCategorySub int       `json:"id_clothing_category_sub"`
-	ClothesSizeName       string    `json:"clothes_size_name"`
-	ClothesSizeNotes      string    `json:"clothes_size_notes"`
-	ClothesSizeStatus     int       `json:"clothes_size_status"`
-	CreatedAt             time.Time `json:"created_at"`
-	UpdatedAt             time.Time `json:"updated_at"`
+	ID                       int                       `json:"id"`
+	IDClothingCategorySub    int                       `json:"id_clothing_category_sub"`
+	ClothesSizeName          string                    `json:"clothes_size_name"`
+	ClothesSizeNotes         string                    `json:"clothes_size_notes"`
+	ClothesSizeStatus        int                       `json:"clothes_size_status"`
+	IDClothingSizeChartEntry *int                      `json:"id_clothing_size_chart_entry"`
+	Measurements             []ClothingSizeMeasurement `json:"measurements"`
+	CreatedAt                time.Time                 `json:"created_at"`
+	UpdatedAt                time.Time                 `json:"updated_at"`
+}
+
+// ClothingSizeMeasurement is a measurement of a size converted to MeasurementUnit, a single value has Min equal to Max
+type ClothingSizeMeasurement struct {
+	MeasurementType string  `json:"measurement_type"`
+	MeasurementMin  float64 `json:"measurement_min"`
+	MeasurementMax  float64 `json:"measurement_max"`
+	MeasurementUnit string  `json:"measurement_unit"`
+}
+
+// ClothingSizeRequest creates or updates a size. Measurements replaces all measurements of the size,
+// leave it out to keep them and send [] to clear them.
+type ClothingSizeRequest struct {
+	IDClothingCategorySub int                      `json:"id_clothing_category_sub" binding:"required"`
+	ClothesSizeName       string                   `json:"clothes_size_name" binding:"required,max=8"`
+	ClothesSizeNotes      string                   `json:"clothes_size_notes" binding:"max=256"`
+	Measurements          []SizeMeasurementRequest `json:"measurements" binding:"omitempty,dive"`
+}
+
+// SizeMeasurementRequest sets one measurement, either bound may be left out for a single value.
+// The unit defaults to cm.
+type SizeMeasurementRequest struct {
+	MeasurementType string   `json:"measurement_type" binding:"required"`
+	MeasurementMin  *float64 `json:"measurement_min"`
+	MeasurementMax  *float64 `json:"measurement_max"`
+	MeasurementUnit string   `json:"measurement_unit"`
+}
+
+type ClothingSizeChart struct {
+	ID              int                      `json:"id"`
+	SizeChartName   string                   `json:"size_chart_name"`
+	SizeChartNotes  string                   `json:"size_chart_notes"`
+	SizeChartStatus int                      `json:"size_chart_status"`
+	Entries         []ClothingSizeChartEntry `json:"entries"`
+	CreatedAt       time.Time                `json:"created_at"`
+	UpdatedAt       time.Time                `json:"updated_at"`
+}
+
+type ClothingSizeChartEntry struct {
+	ID                  int       `json:"id"`
+	IDClothingSizeChart int       `json:"id_clothing_size_chart"`
+	SizeChartEntryName  string    `json:"size_chart_entry_name"`
+	SizeChartEntryNotes string    `json:"size_chart_entry_notes"`
+	SizeChartEntryOrder int       `json:"size_chart_entry_order"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+type SizeChartEntryRequest struct {
+	// ID keeps an existing entry, so renaming it renames the sizes generated from it. Leave it out for new entries.
+	ID                  int    `json:"id"`
+	SizeChartEntryName  string `json:"size_chart_entry_name" binding:"required,max=8"`
+	SizeChartEntryNotes string `json:"size_chart_entry_notes" binding:"max=256"`
+}
+
+type SizeChartRequest struct {
+	SizeChartName  string                  `json:"size_chart_name" binding:"required,max=64"`
+	SizeChartNotes string                  `json:"size_chart_notes" binding:"max=256"`
+	Entries        []SizeChartEntryRequest `json:"entries" binding:"required,min=1,dive"`
+	// Propagate applies the changed entries to every subcategory the chart was applied to
+	Propagate bool `json:"propagate"`
+}
+
+type ApplySizeChartRequest struct {
+	IDClothingSizeChart int `json:"id_clothing_size_chart" binding:"required"`
 }
 
 type ClothingInventoryMovement struct {
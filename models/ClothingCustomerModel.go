@@ -16,3 +16,13 @@ type ClothingCustomer struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
+
+// ClothingCustomerRequest creates a customer
+type ClothingCustomerRequest struct {
+	CustName    string `json:"cust_name" binding:"required,notblank,max=64"`
+	CustAddress string `json:"cust_address" binding:"required,notblank,max=256"`
+	CustCity    string `json:"cust_city" binding:"required,notblank,max=64"`
+	CustPhone   string `json:"cust_phone" binding:"required,phone,max=16"`
+	CustEmail   string `json:"cust_email" binding:"required,email,max=128"`
+	CustNotes   string `json:"cust_notes" binding:"max=256"`
+}
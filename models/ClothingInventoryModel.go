@@ -0,0 +1,17 @@
+package models
+
+// InventoryLevel is the stock of a size computed from the inventory movements and open rentals.
+// OnHand is what is physically at the location, Available is what can still be rented out:
+// OnHand = Available + Reserved, and OnHand + RentedOut is all stock the business owns.
+type InventoryLevel struct {
+	IDClothingCategory    int    `json:"id_clothing_category"`
+	ClothesCatName        string `json:"clothes_cat_name"`
+	IDClothingCategorySub int    `json:"id_clothing_category_sub"`
+	ClothesCatNameSub     string `json:"clothes_cat_name_sub"`
+	IDClothingSize        int    `json:"id_clothing_size"`
+	ClothesSizeName       string `json:"clothes_size_name"`
+	OnHand                int    `json:"on_hand"`
+	RentedOut             int    `json:"rented_out"`
+	Reserved              int    `json:"reserved"`
+	Available             int    `json:"available"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+type ClothingLocation struct {
+	ID              int       `json:"id"`
+	LocationName    string    `json:"location_name"`
+	LocationType    int       `json:"location_type"`
+	LocationAddress string    `json:"location_address"`
+	LocationStatus  int       `json:"location_status"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// LocationRequest creates or updates a location, the name has to be unique regardless of case.
+// The type defaults to a store.
+type LocationRequest struct {
+	LocationName    string `json:"location_name" binding:"required,notblank,max=64"`
+	LocationType    int    `json:"location_type" binding:"omitempty,oneof=1 2"`
+	LocationAddress string `json:"location_address" binding:"max=256"`
+}
+
+// StockTransferRequest moves stock of a size from one location to another
+type StockTransferRequest struct {
+	IDClothingSize      int    `json:"id_clothing_size" binding:"required"`
+	FromLocation        int    `json:"from_location" binding:"required"`
+	ToLocation          int    `json:"to_location" binding:"required"`
+	ClothesQty          int    `json:"clothes_qty" binding:"required,min=1"`
+	ClothesTransferNote string `json:"clothes_transfer_note" binding:"max=256"`
+}
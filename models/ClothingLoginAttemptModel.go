@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+type ClothingLoginAttempt struct {
+	ID                   int        `json:"id"`
+	AttemptKeyType       int        `json:"attempt_key_type"`
+	AttemptKeyTypeStr    string     `json:"attempt_key_type_str"`
+	AttemptKey           string     `json:"attempt_key"`
+	AttemptFailedCount   int        `json:"attempt_failed_count"`
+	AttemptLastFailedAt  time.Time  `json:"attempt_last_failed_at"`
+	AttemptLockedUntil   *time.Time `json:"attempt_locked_until"`
+	AttemptSuspendedUser bool       `json:"attempt_suspended_user"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
@@ -9,6 +9,7 @@ type ClothingRental struct {
 	IDClothingCategorySub       int       `json:"id_clothing_category_sub"`
 	IDClothingSize              int       `json:"id_clothing_size"`
 	IDClothingCustomer          int       `json:"id_clothing_customer"`
+	IDClothingLocation          int       `json:"id_clothing_location"`
 	ClothesQtyRent              int       `json:"clothes_qty_rent"`
 	ClothesQtyReturn            int       `json:"clothes_qty_return"`
 	ClothesRentDateBegin        time.Time `json:"clothes_rent_date_begin"`
@@ -20,16 +21,20 @@ type ClothingRental struct {
 	UpdatedAt                   time.Time `json:"updated_at"`
 }
 
+// RentalRequest hands out a size, the stock leaves the location of the subcategory unless another is given
 type RentalRequest struct {
 	IDClothingCategorySub int    `json:"id_clothing_category_sub" binding:"required"`
 	IDClothingSize        int    `json:"id_clothing_size" binding:"required"`
 	IDClothingCustomer    int    `json:"id_clothing_customer" binding:"required"`
-	ClothesQtyRent        int    `json:"clothes_qty_rent" binding:"required"`
+	ClothesQtyRent        int    `json:"clothes_qty_rent" binding:"required,min=1"`
 	RentDateBegin         string `json:"rent_date_begin" binding:"required"`
 	RentDateEnd           string `json:"rent_date_end" binding:"required"`
+	IDClothingLocation    int    `json:"id_clothing_location"`
 }
 
+// ReturnRequest takes a rental back, the stock returns to the location it was handed out from unless another is given
 type ReturnRequest struct {
-	RentalID         int `json:"rental_id" binding:"required"`
-	ClothesQtyReturn int `json:"clothes_qty_return" binding:"required"`
+	RentalID           int `json:"rental_id" binding:"required"`
+	ClothesQtyReturn   int `json:"clothes_qty_return" binding:"required,min=1"`
+	IDClothingLocation int `json:"id_clothing_location"`
 }
@@ -0,0 +1,10 @@
+package models
+
+// SearchHit is one ranked search result. Snippet is HTML escaped with the matched words wrapped in <mark>.
+type SearchHit struct {
+	ID       int     `json:"id"`
+	Title    string  `json:"title"`
+	Subtitle string  `json:"subtitle"`
+	Snippet  string  `json:"snippet"`
+	Score    float64 `json:"score"`
+}
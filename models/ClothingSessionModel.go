@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+type ClothingSession struct {
+	ID                int       `json:"id"`
+	IDClothingUsers   int       `json:"id_clothing_users"`
+	SessionUserAgent  string    `json:"session_user_agent"`
+	SessionClientIP   string    `json:"session_client_ip"`
+	SessionStatus     int       `json:"session_status"`
+	SessionStatusStr  string    `json:"session_status_str,omitempty"`
+	SessionExpiresAt  time.Time `json:"session_expires_at"`
+	SessionLastSeenAt time.Time `json:"session_last_seen_at"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	// Current marks the session the listing request was made with
+	Current bool `json:"current"`
+}
@@ -5,10 +5,59 @@ import (
 )
 
 type ClothingUser struct {
-	ID         int       `json:"id"`
-	Username   string    `json:"username"`
-	Pin        int       `json:"pin"`
-	UserStatus int       `json:"user_status"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID            int       `json:"id"`
+	Username      string    `json:"username"`
+	PinHash       string    `json:"-"`
+	UserStatus    int       `json:"user_status"`
+	UserRole      int       `json:"user_role"`
+	MustChangePin bool      `json:"must_change_pin"`
+	TotpEnabled   bool      `json:"totp_enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required,max=32"`
+	Pin      string `json:"pin" binding:"required,len=6,numeric"`
+	UserRole int    `json:"user_role" binding:"required,min=1,max=4"`
+}
+
+type UpdateUserRequest struct {
+	Username string `json:"username" binding:"required,max=32"`
+	UserRole int    `json:"user_role" binding:"required,min=1,max=4"`
+}
+
+type ResetPinRequest struct {
+	Pin string `json:"pin" binding:"required,len=6,numeric"`
+}
+
+type ChangePinRequest struct {
+	CurrentPin string `json:"current_pin" binding:"required,len=6,numeric"`
+	NewPin     string `json:"new_pin" binding:"required,len=6,numeric"`
+}
+
+type TotpCodeRequest struct {
+	// Code is either the current 6 digit TOTP code or one of the recovery codes
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+type TotpEnrollRequest struct {
+	Pin string `json:"pin" binding:"required,len=6,numeric"`
+}
+
+type TotpConfirmRequest struct {
+	Pin  string `json:"pin" binding:"required,len=6,numeric"`
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type TotpDisableRequest struct {
+	Pin string `json:"pin" binding:"required,len=6,numeric"`
+	// Code is either the current 6 digit TOTP code or one of the recovery codes
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+type TotpLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	// Code is either the current 6 digit TOTP code or one of the recovery codes
+	Code string `json:"code" binding:"required,max=32"`
 }
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// SeaweedStorage keeps files in SeaweedFS. The master assigns a file id and tells which
+// volume server holds it, the data itself is written to and read from that volume server.
+// The key is the directory of the name followed by the file id and the extension,
+// e.g. "pictures/12/3,01637037d6.jpg".
+type SeaweedStorage struct {
+	MasterURL string
+	Client    *http.Client
+}
+
+// NewSeaweedStorage talks to the master at masterURL. Pass an http.Client pointing at
+// an in-process server to run against a fake SeaweedFS.
+func NewSeaweedStorage(masterURL string, client *http.Client) *SeaweedStorage {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &SeaweedStorage{MasterURL: strings.TrimRight(masterURL, "/"), Client: client}
+}
+
+type seaweedAssignResponse struct {
+	Fid       string `json:"fid"`
+	URL       string `json:"url"`
+	PublicURL string `json:"publicUrl"`
+	Error     string `json:"error"`
+}
+
+type seaweedLookupResponse struct {
+	Locations []struct {
+		URL       string `json:"url"`
+		PublicURL string `json:"publicUrl"`
+	} `json:"locations"`
+	Error string `json:"error"`
+}
+
+// fid extracts the SeaweedFS file id from a key
+func (s *SeaweedStorage) fid(key string) (string, error) {
+	base := path.Base(key)
+	fid := strings.TrimSuffix(base, path.Ext(base))
+	if !strings.Contains(fid, ",") {
+		return "", fmt.Errorf("storage: invalid seaweed key %q", key)
+	}
+	return fid, nil
+}
+
+// volumeURL builds the URL of a file on a volume server, using the scheme of the master
+func (s *SeaweedStorage) volumeURL(volume, fid string) string {
+	scheme := "http"
+	if u, err := url.Parse(s.MasterURL); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	}
+	return scheme + "://" + volume + "/" + fid
+}
+
+func (s *SeaweedStorage) getJSON(endpoint string, target interface{}) error {
+	resp, err := s.Client.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("storage: seaweed master returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// lookup returns the volume server holding a file id
+func (s *SeaweedStorage) lookup(fid string) (string, error) {
+	volumeID := fid[:strings.Index(fid, ",")]
+
+	var lookup seaweedLookupResponse
+	if err := s.getJSON(s.MasterURL+"/dir/lookup?volumeId="+url.QueryEscape(volumeID), &lookup); err != nil {
+		return "", err
+	}
+	if lookup.Error != "" || len(lookup.Locations) == 0 {
+		return "", ErrNotFound
+	}
+	return lookup.Locations[0].URL, nil
+}
+
+func (s *SeaweedStorage) Put(name string, data []byte, contentType string) (string, error) {
+	var assign seaweedAssignResponse
+	if err := s.getJSON(s.MasterURL+"/dir/assign", &assign); err != nil {
+		return "", err
+	}
+	if assign.Error != "" || assign.Fid == "" {
+		return "", fmt.Errorf("storage: seaweed assign failed: %s", assign.Error)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, path.Base(name)))
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.volumeURL(assign.URL, assign.Fid), &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("storage: seaweed upload returned %s", resp.Status)
+	}
+
+	return path.Join(path.Dir(name), assign.Fid+path.Ext(name)), nil
+}
+
+func (s *SeaweedStorage) Get(key string) (*Object, error) {
+	fid, err := s.fid(key)
+	if err != nil {
+		return nil, err
+	}
+	volume, err := s.lookup(fid)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Client.Get(s.volumeURL(volume, fid))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("storage: seaweed read returned %s", resp.Status)
+	}
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+
+	return &Object{
+		Body:        resp.Body,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ModTime:     modTime,
+	}, nil
+}
+
+func (s *SeaweedStorage) Delete(key string) error {
+	fid, err := s.fid(key)
+	if err != nil {
+		return err
+	}
+	volume, err := s.lookup(fid)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, s.volumeURL(volume, fid), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("storage: seaweed delete returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testFid = "3,01637037d6"
+
+// fakeSeaweed plays both the master and the volume server. status makes a step answer
+// with an error, keyed by "assign", "lookup", "upload", "read" or "delete".
+type fakeSeaweed struct {
+	server *httptest.Server
+	status map[string]int
+
+	mu          sync.Mutex
+	files       map[string][]byte
+	types       map[string]string
+	unknownVols bool
+}
+
+func newFakeSeaweed(t *testing.T) *fakeSeaweed {
+	f := &fakeSeaweed{
+		status: map[string]int{},
+		files:  map[string][]byte{},
+		types:  map[string]string{},
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeSeaweed) host() string {
+	return strings.TrimPrefix(f.server.URL, "http://")
+}
+
+func (f *fakeSeaweed) fail(w http.ResponseWriter, step string) bool {
+	if status := f.status[step]; status != 0 {
+		w.WriteHeader(status)
+		return true
+	}
+	return false
+}
+
+func (f *fakeSeaweed) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/dir/assign":
+		if f.fail(w, "assign") {
+			return
+		}
+		_ = json.NewEncoder(w).Encode(seaweedAssignResponse{Fid: testFid, URL: f.host()})
+
+	case r.URL.Path == "/dir/lookup":
+		if f.fail(w, "lookup") {
+			return
+		}
+		if f.unknownVols || r.URL.Query().Get("volumeId") != "3" {
+			_ = json.NewEncoder(w).Encode(seaweedLookupResponse{Error: "volume id not found"})
+			return
+		}
+		var lookup seaweedLookupResponse
+		lookup.Locations = append(lookup.Locations, struct {
+			URL       string `json:"url"`
+			PublicURL string `json:"publicUrl"`
+		}{URL: f.host()})
+		_ = json.NewEncoder(w).Encode(lookup)
+
+	case r.Method == http.MethodPost:
+		if f.fail(w, "upload") {
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		fid := strings.TrimPrefix(r.URL.Path, "/")
+		f.files[fid] = data
+		f.types[fid] = header.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusCreated)
+
+	case r.Method == http.MethodGet:
+		if f.fail(w, "read") {
+			return
+		}
+		fid := strings.TrimPrefix(r.URL.Path, "/")
+		data, ok := f.files[fid]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[fid])
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2026 07:28:00 GMT")
+		_, _ = w.Write(data)
+
+	case r.Method == http.MethodDelete:
+		if f.fail(w, "delete") {
+			return
+		}
+		delete(f.files, strings.TrimPrefix(r.URL.Path, "/"))
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func TestSeaweedStoragePutGetDelete(t *testing.T) {
+	fake := newFakeSeaweed(t)
+	store := NewSeaweedStorage(fake.server.URL+"/", fake.server.Client())
+
+	key, err := store.Put("pictures/12/1-abc.jpg", []byte("jpeg data"), "image/jpeg")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if want := "pictures/12/" + testFid + ".jpg"; key != want {
+		t.Fatalf("Put key = %q, want %q", key, want)
+	}
+
+	object, err := store.Get(key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(object.Body)
+	object.Body.Close()
+	if string(data) != "jpeg data" {
+		t.Errorf("Get body = %q", data)
+	}
+	if object.ContentType != "image/jpeg" {
+		t.Errorf("Get content type = %q", object.ContentType)
+	}
+	if object.ModTime.Year() != 2026 {
+		t.Errorf("Get mod time = %v", object.ModTime)
+	}
+
+	if err := store.Delete(key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSeaweedStorageErrors(t *testing.T) {
+	const key = "pictures/12/" + testFid + ".jpg"
+
+	tests := []struct {
+		name        string
+		status      map[string]int
+		unknownVols bool
+		run         func(s *SeaweedStorage) error
+		notFound    bool
+		ok          bool
+	}{
+		{
+			name:   "assign fails",
+			status: map[string]int{"assign": http.StatusServiceUnavailable},
+			run: func(s *SeaweedStorage) error {
+				_, err := s.Put("pictures/12/1-abc.jpg", []byte("x"), "image/jpeg")
+				return err
+			},
+		},
+		{
+			name:   "upload fails",
+			status: map[string]int{"upload": http.StatusInternalServerError},
+			run: func(s *SeaweedStorage) error {
+				_, err := s.Put("pictures/12/1-abc.jpg", []byte("x"), "image/jpeg")
+				return err
+			},
+		},
+		{
+			name:   "read fails",
+			status: map[string]int{"read": http.StatusInternalServerError},
+			run: func(s *SeaweedStorage) error {
+				_, err := s.Get(key)
+				return err
+			},
+		},
+		{
+			name:     "read of a missing file",
+			run:      func(s *SeaweedStorage) error { _, err := s.Get("pictures/12/3,ffff.jpg"); return err },
+			notFound: true,
+		},
+		{
+			name:     "lookup answers 404",
+			status:   map[string]int{"lookup": http.StatusNotFound},
+			run:      func(s *SeaweedStorage) error { _, err := s.Get(key); return err },
+			notFound: true,
+		},
+		{
+			name:        "lookup of an unknown volume",
+			unknownVols: true,
+			run:         func(s *SeaweedStorage) error { _, err := s.Get(key); return err },
+			notFound:    true,
+		},
+		{
+			name:   "lookup fails",
+			status: map[string]int{"lookup": http.StatusInternalServerError},
+			run:    func(s *SeaweedStorage) error { _, err := s.Get(key); return err },
+		},
+		{
+			name: "invalid key",
+			run:  func(s *SeaweedStorage) error { _, err := s.Get("pictures/12/1-abc.jpg"); return err },
+		},
+		{
+			name:   "delete fails",
+			status: map[string]int{"delete": http.StatusInternalServerError},
+			run:    func(s *SeaweedStorage) error { return s.Delete(key) },
+		},
+		{
+			name:        "delete of an unknown volume",
+			unknownVols: true,
+			run:         func(s *SeaweedStorage) error { return s.Delete(key) },
+			ok:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeSeaweed(t)
+			fake.files[testFid] = []byte("jpeg data")
+			fake.unknownVols = tt.unknownVols
+			for step, status := range tt.status {
+				fake.status[step] = status
+			}
+
+			err := tt.run(NewSeaweedStorage(fake.server.URL, fake.server.Client()))
+			switch {
+			case tt.ok:
+				if err != nil {
+					t.Errorf("err = %v, want nil", err)
+				}
+			case tt.notFound:
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("err = %v, want ErrNotFound", err)
+				}
+			default:
+				if err == nil || errors.Is(err, ErrNotFound) {
+					t.Errorf("err = %v, want a storage error", err)
+				}
+			}
+		})
+	}
+}
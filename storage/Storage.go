@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	BACKEND_LOCAL   = "local"
+	BACKEND_SEAWEED = "seaweed"
+)
+
+// ErrNotFound is returned when a key does not resolve to a stored object
+var ErrNotFound = errors.New("storage: object not found")
+
+// Object is a stored file opened for reading, the caller has to close Body
+type Object struct {
+	Body        io.ReadCloser
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Storage keeps uploaded files such as garment pictures and documents.
+// Put receives a slash separated name like "pictures/12/1-abc.jpg" and returns the key
+// to read the file back with. The key keeps the directory and extension of the name,
+// so callers can recognise their own keys, but is otherwise chosen by the backend.
+type Storage interface {
+	Put(name string, data []byte, contentType string) (string, error)
+	Get(key string) (*Object, error)
+	Delete(key string) error
+}
+
+// Store is the backend selected by InitStorage
+var Store Storage
+
+// InitStorage selects the storage backend, an empty backend means the local file system
+func InitStorage(backend, localRoot, seaweedURL string) error {
+	switch strings.ToLower(backend) {
+	case "", BACKEND_LOCAL:
+		Store = NewLocalStorage(localRoot)
+	case BACKEND_SEAWEED:
+		if seaweedURL == "" {
+			return errors.New("storage: seaweed backend selected but seaweed_url is empty")
+		}
+		Store = NewSeaweedStorage(seaweedURL, &http.Client{Timeout: 30 * time.Second})
+	default:
+		return fmt.Errorf("storage: unknown backend %q", backend)
+	}
+	return nil
+}
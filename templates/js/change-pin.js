@@ -0,0 +1,110 @@
+document.addEventListener('DOMContentLoaded', function() {
+    const changePinForm = document.getElementById('changePinForm');
+    const currentPinInput = document.getElementById('currentPin');
+    const newPinInput = document.getElementById('newPin');
+    const confirmPinInput = document.getElementById('confirmPin');
+    const errorMessage = document.getElementById('error-message');
+    const submitBtn = changePinForm.querySelector('button[type="submit"]');
+
+    // Only allow digits in every PIN field
+    [currentPinInput, newPinInput, confirmPinInput].forEach(input => {
+        input.addEventListener('input', function() {
+            this.value = this.value.replace(/\D/g, '');
+            setFieldError(this, '');
+        });
+    });
+
+    function setFieldError(input, message) {
+        document.getElementById(`${input.id}-error`).textContent = message;
+        if (message) {
+            input.classList.add('error');
+        } else {
+            input.classList.remove('error');
+        }
+    }
+
+    // Mirrors the server side rule: no repeated digit and no ascending or descending run
+    function isWeakPin(pin) {
+        let same = true, up = true, down = true;
+        for (let i = 1; i < pin.length; i++) {
+            const diff = pin.charCodeAt(i) - pin.charCodeAt(i - 1);
+            if (diff !== 0) same = false;
+            if (diff !== 1) up = false;
+            if (diff !== -1) down = false;
+        }
+        return same || up || down;
+    }
+
+    function validate() {
+        let valid = true;
+
+        if (!/^\d{6}$/.test(currentPinInput.value)) {
+            setFieldError(currentPinInput, 'PIN must be exactly 6 digits');
+            valid = false;
+        }
+
+        if (!/^\d{6}$/.test(newPinInput.value)) {
+            setFieldError(newPinInput, 'PIN must be exactly 6 digits');
+            valid = false;
+        } else if (isWeakPin(newPinInput.value)) {
+            setFieldError(newPinInput, 'PIN is too weak, avoid repeated digits and sequences');
+            valid = false;
+        } else if (newPinInput.value === currentPinInput.value) {
+            setFieldError(newPinInput, 'New PIN must be different from the current PIN');
+            valid = false;
+        }
+
+        if (confirmPinInput.value !== newPinInput.value) {
+            setFieldError(confirmPinInput, 'PINs do not match');
+            valid = false;
+        }
+
+        return valid;
+    }
+
+    function showErrorMessage(message) {
+        errorMessage.textContent = message;
+        errorMessage.style.display = 'block';
+    }
+
+    changePinForm.addEventListener('submit', async function(e) {
+        e.preventDefault();
+        errorMessage.style.display = 'none';
+
+        if (!validate()) {
+            return;
+        }
+
+        submitBtn.disabled = true;
+        submitBtn.textContent = 'Saving...';
+
+        try {
+            const response = await fetch('/api/auth/change-pin', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json'
+                },
+                credentials: 'same-origin',
+                body: JSON.stringify({
+                    current_pin: currentPinInput.value,
+                    new_pin: newPinInput.value
+                })
+            });
+
+            const data = await response.json();
+
+            if (response.ok) {
+                window.location.href = '/';
+            } else {
+                showErrorMessage(data.error || 'Failed to change PIN');
+                submitBtn.disabled = false;
+                submitBtn.textContent = 'Change PIN';
+            }
+        } catch (error) {
+            console.error('Change PIN error:', error);
+            showErrorMessage('An error occurred. Please try again.');
+            submitBtn.disabled = false;
+            submitBtn.textContent = 'Change PIN';
+        }
+    });
+});
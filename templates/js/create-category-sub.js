@@ -11,6 +11,7 @@ const subCategoryNameInput = document.getElementById('subCategoryName');
 const subCategoryLocationInput = document.getElementById('subCategoryLocation');
 const nameCount = document.getElementById('nameCount');
 const locationCount = document.getElementById('locationCount');
+const locationOptions = document.getElementById('locationOptions');
 const messageDiv = document.getElementById('message');
 const loadingDiv = document.getElementById('loading');
 const submitBtn = document.getElementById('submitBtn');
@@ -19,14 +20,11 @@ const loadingContainer = document.getElementById('loadingContainer');
 const createdAtSpan = document.getElementById('createdAt');
 const updatedAtSpan = document.getElementById('updatedAt');
 
-// Store base64 images
-const pictureData = {
-    picture1: null,
-    picture2: null,
-    picture3: null,
-    picture4: null,
-    picture5: null
-};
+// Pictures are uploaded separately once the subcategory is saved.
+// pictureFiles holds the newly selected file per slot, existingPictures the slots already stored on the server.
+const pictureFiles = {};
+const existingPictures = {};
+const removedPictures = new Set();
 
 // Store data for display
 let categories = [];
@@ -34,6 +32,7 @@ let categories = [];
 // Load data on page load
 document.addEventListener('DOMContentLoaded', function() {
     loadCategories();
+    loadLocations();
     if (isEditMode) {
         loadSubcategoryData();
     }
@@ -68,12 +67,11 @@ async function loadSubcategoryData() {
 
             // Load existing pictures
             for (let i = 1; i <= 5; i++) {
-                const pictureKey = `picture${i}`;
-                const base64Data = subcategory[`clothes_picture_${i}`];
-                if (base64Data) {
-                    pictureData[pictureKey] = base64Data;
+                const pictureUrl = subcategory[`clothes_picture_${i}`];
+                if (pictureUrl) {
+                    existingPictures[i] = true;
                     const preview = document.getElementById(`preview${i}`);
-                    preview.style.backgroundImage = `url(${base64Data})`;
+                    preview.style.backgroundImage = `url(${pictureUrl})`;
                     preview.classList.add('active');
                 }
             }
@@ -153,9 +151,8 @@ function updateCharCount(input, counter, maxLength) {
 // Load categories from API
 async function loadCategories() {
     try {
-        const response = await fetch('/api/categories');
-        if (response.ok) {
-            categories = await response.json();
+        categories = await fetchAllPages('/api/categories');
+        if (categories) {
             categories.forEach(category => {
                 const option = document.createElement('option');
                 option.value = category.id;
@@ -176,6 +173,22 @@ async function loadCategories() {
     }
 }
 
+// Load the active locations the location input suggests, the server only accepts one of them
+async function loadLocations() {
+    try {
+        const locations = await fetchAllPages('/api/locations');
+        if (locations) {
+            locations.forEach(location => {
+                const option = document.createElement('option');
+                option.value = location.location_name;
+                locationOptions.appendChild(option);
+            });
+        }
+    } catch (error) {
+        showMessage(`Error loading locations: ${error.message}`, 'error');
+    }
+}
+
 // Character counter for subcategory name
 subCategoryNameInput.addEventListener('input', function() {
     updateCharCount(this, nameCount, 32);
@@ -207,11 +220,12 @@ for (let i = 1; i <= 5; i++) {
                 return;
             }
 
+            pictureFiles[i] = file;
+            removedPictures.delete(i);
+
             const reader = new FileReader();
             reader.onload = function(event) {
-                const base64 = event.target.result;
-                pictureData[`picture${i}`] = base64;
-                preview.style.backgroundImage = `url(${base64})`;
+                preview.style.backgroundImage = `url(${event.target.result})`;
                 preview.classList.add('active');
             };
             reader.readAsDataURL(file);
@@ -220,7 +234,10 @@ for (let i = 1; i <= 5; i++) {
 
     removeBtn.addEventListener('click', function() {
         input.value = '';
-        pictureData[`picture${i}`] = null;
+        pictureFiles[i] = null;
+        if (existingPictures[i]) {
+            removedPictures.add(i);
+        }
         preview.style.backgroundImage = '';
         preview.classList.remove('active');
     });
@@ -257,18 +274,14 @@ form.addEventListener('submit', async function(e) {
     const formData = {
         id_clothing_category: parseInt(parentCategorySelect.value),
         clothes_cat_name_sub: subCategoryNameInput.value.trim(),
-        clothes_cat_location_sub: subCategoryLocationInput.value.trim(),
-        clothes_picture_1: pictureData.picture1 || '',
-        clothes_picture_2: pictureData.picture2 || '',
-        clothes_picture_3: pictureData.picture3 || '',
-        clothes_picture_4: pictureData.picture4 || '',
-        clothes_picture_5: pictureData.picture5 || ''
+        clothes_cat_location_sub: subCategoryLocationInput.value.trim()
     };
 
     // Show loading
     loadingDiv.classList.add('show');
     submitBtn.disabled = true;
     messageDiv.style.display = 'none';
+    clearFieldErrors(form);
 
     try {
         let response;
@@ -297,6 +310,14 @@ form.addEventListener('submit', async function(e) {
         const data = await response.json();
 
         if (response.ok) {
+            // Upload the selected pictures and drop the removed ones now that the subcategory exists
+            const subcategoryId = isEditMode ? subcategoryIdInput.value : data.id;
+            const pictureError = await syncPictures(subcategoryId);
+            if (pictureError) {
+                showMessage(`Error: ${pictureError}`, 'error');
+                return;
+            }
+
             const successMessage = isEditMode ? 'Subcategory updated successfully!' : 'Subcategory created successfully!';
             showMessage(successMessage, 'success');
 
@@ -310,7 +331,7 @@ form.addEventListener('submit', async function(e) {
                     const preview = document.getElementById(`preview${i}`);
                     preview.style.backgroundImage = '';
                     preview.classList.remove('active');
-                    pictureData[`picture${i}`] = null;
+                    pictureFiles[i] = null;
                 }
             }
 
@@ -320,6 +341,7 @@ form.addEventListener('submit', async function(e) {
             }, 2000);
         } else {
             const errorMessage = isEditMode ? 'Failed to update subcategory' : 'Failed to create subcategory';
+            showFieldErrors(form, data.fields);
             showMessage(`Error: ${data.error || errorMessage}`, 'error');
         }
     } catch (error) {
@@ -330,6 +352,32 @@ form.addEventListener('submit', async function(e) {
     }
 });
 
+// Upload new pictures and delete removed ones, returns an error message or null
+async function syncPictures(subcategoryId) {
+    for (let i = 1; i <= 5; i++) {
+        let response = null;
+
+        if (pictureFiles[i]) {
+            const body = new FormData();
+            body.append('picture', pictureFiles[i]);
+            response = await fetch(`/api/categories-sub/${subcategoryId}/pictures/${i}`, {
+                method: 'POST',
+                body: body
+            });
+        } else if (removedPictures.has(i)) {
+            response = await fetch(`/api/categories-sub/${subcategoryId}/pictures/${i}`, {
+                method: 'DELETE'
+            });
+        }
+
+        if (response && !response.ok) {
+            const data = await response.json();
+            return `Picture ${i}: ${data.error || 'Failed to save picture'}`;
+        }
+    }
+    return null;
+}
+
 // Helper function to show messages
 function showMessage(text, type) {
     messageDiv.textContent = text;
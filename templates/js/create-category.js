@@ -137,6 +137,7 @@ form.addEventListener('submit', async function(e) {
     loadingDiv.classList.add('show');
     submitBtn.disabled = true;
     messageDiv.style.display = 'none';
+    clearFieldErrors(form);
 
     try {
         let response;
@@ -180,6 +181,7 @@ form.addEventListener('submit', async function(e) {
             }, 2000);
         } else {
             const errorMessage = isEditMode ? 'Failed to update category' : 'Failed to create category';
+            showFieldErrors(form, data.fields);
             showMessage(`Error: ${data.error || errorMessage}`, 'error');
         }
     } catch (error) {
@@ -116,6 +116,7 @@ form.addEventListener('submit', async function(e) {
     loadingDiv.classList.add('show');
     submitBtn.disabled = true;
     messageDiv.style.display = 'none';
+    clearFieldErrors(form);
 
     try {
         const response = await fetch('/api/customers', {
@@ -145,6 +146,7 @@ form.addEventListener('submit', async function(e) {
                 window.location.href = '/';
             }, 2000);
         } else {
+            showFieldErrors(form, data.fields);
             showMessage(`Error: ${data.error || 'Failed to create customer'}`, 'error');
         }
     } catch (error) {
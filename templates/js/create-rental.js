@@ -17,6 +17,7 @@ const infoBox = document.getElementById('infoBox');
 const infoCustomer = document.getElementById('infoCustomer');
 const infoItem = document.getElementById('infoItem');
 const infoSize = document.getElementById('infoSize');
+const infoAvailable = document.getElementById('infoAvailable');
 const infoQuantity = document.getElementById('infoQuantity');
 const infoPeriod = document.getElementById('infoPeriod');
 const infoDuration = document.getElementById('infoDuration');
@@ -26,6 +27,8 @@ let customers = [];
 let categories = [];
 let subcategories = [];
 let sizes = [];
+// Stock available per size id at the location of the selected subcategory
+let availability = {};
 
 // Load data on page load
 document.addEventListener('DOMContentLoaded', function() {
@@ -46,9 +49,8 @@ function setMinDateTime() {
 // Load customers from API
 async function loadCustomers() {
     try {
-        const response = await fetch('/api/customers');
-        if (response.ok) {
-            customers = await response.json();
+        customers = await fetchAllPages('/api/customers');
+        if (customers) {
             customers.forEach(customer => {
                 const option = document.createElement('option');
                 option.value = customer.id;
@@ -66,9 +68,8 @@ async function loadCustomers() {
 // Load categories from API
 async function loadCategories() {
     try {
-        const response = await fetch('/api/categories');
-        if (response.ok) {
-            categories = await response.json();
+        categories = await fetchAllPages('/api/categories');
+        if (categories) {
             categories.forEach(category => {
                 const option = document.createElement('option');
                 option.value = category.id;
@@ -93,9 +94,8 @@ categorySelect.addEventListener('change', async function() {
     if (!this.value) return;
 
     try {
-        const response = await fetch('/api/categories-sub');
-        if (response.ok) {
-            const allSubcategories = await response.json();
+        const allSubcategories = await fetchAllPages(`/api/categories-sub?category_id=${this.value}`);
+        if (allSubcategories) {
             subcategories = allSubcategories.filter(sub => sub.id_clothing_category === parseInt(this.value));
 
             if (subcategories.length === 0) {
@@ -124,13 +124,14 @@ categorySelect.addEventListener('change', async function() {
 subcategorySelect.addEventListener('change', async function() {
     sizeSelect.innerHTML = '<option value="">Select a size...</option>';
     sizeSelect.disabled = true;
+    availability = {};
 
     if (!this.value) return;
 
     try {
-        const response = await fetch(`/api/sizes?subcategory_id=${this.value}`);
-        if (response.ok) {
-            sizes = await response.json();
+        sizes = await fetchAllPages(`/api/sizes?subcategory_id=${this.value}`);
+        if (sizes) {
+            await loadAvailability(this.value);
 
             // Handle null or empty response
             if (!sizes || sizes.length === 0) {
@@ -143,6 +144,9 @@ subcategorySelect.addEventListener('change', async function() {
                 const option = document.createElement('option');
                 option.value = size.id;
                 option.textContent = size.clothes_size_name;
+                if (size.id in availability) {
+                    option.textContent += ` (${availability[size.id]} available)`;
+                }
                 sizeSelect.appendChild(option);
             });
 
@@ -155,6 +159,26 @@ subcategorySelect.addEventListener('change', async function() {
     }
 });
 
+// Load the stock available per size at the location the subcategory is kept at, rentals are booked out there
+async function loadAvailability(subcategoryId) {
+    const subcategory = subcategories.find(s => s.id === parseInt(subcategoryId));
+    let url = `/api/inventory?subcategory_id=${subcategoryId}`;
+    if (subcategory && subcategory.id_clothing_location) {
+        url += `&location_id=${subcategory.id_clothing_location}`;
+    }
+
+    try {
+        const levels = await fetchAllPages(url);
+        if (levels) {
+            levels.forEach(level => {
+                availability[level.id_clothing_size] = level.available;
+            });
+        }
+    } catch (error) {
+        // Availability is only shown as a hint
+    }
+}
+
 // Update info box
 function updateInfoBox() {
     if (!customerSelect.value || !subcategorySelect.value || !sizeSelect.value) {
@@ -164,11 +188,12 @@ function updateInfoBox() {
 
     const customer = customers.find(c => c.id === parseInt(customerSelect.value));
     const subcategory = subcategories.find(s => s.id === parseInt(subcategorySelect.value));
-    const size = sizeSelect.options[sizeSelect.selectedIndex].text;
+    const size = sizes.find(s => s.id === parseInt(sizeSelect.value));
 
     infoCustomer.textContent = customer ? customer.cust_name : '-';
     infoItem.textContent = subcategory ? subcategory.clothes_cat_name_sub : '-';
-    infoSize.textContent = size;
+    infoSize.textContent = size ? size.clothes_size_name : '-';
+    infoAvailable.textContent = sizeSelect.value in availability ? availability[sizeSelect.value] : '-';
     infoQuantity.textContent = quantityInput.value;
 
     if (rentDateBeginInput.value && rentDateEndInput.value) {
@@ -0,0 +1,27 @@
+// Adds the csrf token to every state changing request made with fetch.
+// The server hands the token out in the csrf_token cookie and expects it back in the X-CSRF-Token header.
+(function() {
+    const safeMethods = ['GET', 'HEAD', 'OPTIONS'];
+    const originalFetch = window.fetch;
+
+    function getCsrfToken() {
+        const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]*)/);
+        return match ? decodeURIComponent(match[1]) : '';
+    }
+
+    window.fetch = function(input, init) {
+        init = init || {};
+
+        const method = (init.method || (input instanceof Request ? input.method : 'GET')).toUpperCase();
+        const url = new URL(input instanceof Request ? input.url : input, window.location.href);
+
+        // Only our own origin gets the token
+        if (!safeMethods.includes(method) && url.origin === window.location.origin) {
+            const headers = new Headers(init.headers || (input instanceof Request ? input.headers : undefined));
+            headers.set('X-CSRF-Token', getCsrfToken());
+            init.headers = headers;
+        }
+
+        return originalFetch.call(this, input, init);
+    };
+})();
@@ -0,0 +1,23 @@
+// Shows the field errors the api returns as {"fields": {"<input name>": "<message>"}} under the matching inputs.
+
+function clearFieldErrors(form) {
+    form.querySelectorAll('.field-error').forEach(el => el.remove());
+    form.querySelectorAll('.invalid').forEach(el => el.classList.remove('invalid'));
+}
+
+function showFieldErrors(form, fields) {
+    clearFieldErrors(form);
+    if (!fields) return;
+
+    Object.entries(fields).forEach(([name, message]) => {
+        const input = form.querySelector(`[name="${name}"]`);
+        if (!input) return;
+
+        const error = document.createElement('div');
+        error.className = 'field-error';
+        error.textContent = message.charAt(0).toUpperCase() + message.slice(1);
+
+        input.classList.add('invalid');
+        input.insertAdjacentElement('afterend', error);
+    });
+}
@@ -0,0 +1,21 @@
+// Reads every page of a list endpoint. The api returns at most 1000 rows per request and points at the
+// next page in the X-Next-Cursor header, the pickers follow it so no option is silently left out.
+// Resolves to null when a page fails to load.
+async function fetchAllPages(url) {
+    const rows = [];
+    let cursor = '';
+
+    do {
+        const pageUrl = new URL(url, window.location.href);
+        pageUrl.searchParams.set('limit', '1000');
+        if (cursor) pageUrl.searchParams.set('cursor', cursor);
+
+        const response = await fetch(pageUrl.toString());
+        if (!response.ok) return null;
+
+        rows.push(...(await response.json() || []));
+        cursor = response.headers.get('X-Next-Cursor');
+    } while (cursor);
+
+    return rows;
+}
@@ -124,9 +124,12 @@ document.addEventListener('DOMContentLoaded', function() {
 
             const data = await response.json();
 
-            if (response.ok) {
-                // Success - redirect to index page
-                window.location.href = '/';
+            if (response.ok && data.totp_required) {
+                // The PIN was accepted, ask for the authenticator code next
+                showTotpStep(data.challenge_token);
+            } else if (response.ok) {
+                // Success - a pending PIN change comes first, otherwise go to the index page
+                window.location.href = data.must_change_pin ? '/change-pin' : '/';
             } else {
                 // Show error message
                 showErrorMessage(data.error || 'Failed to authenticate');
@@ -140,6 +143,80 @@ document.addEventListener('DOMContentLoaded', function() {
             loginBtn.textContent = 'Login';
         }
     });
+
+    // Second login step for users with two-factor authentication
+    const totpForm = document.getElementById('totpForm');
+    const totpInput = document.getElementById('totpCode');
+    const totpError = document.getElementById('totp-error');
+    const totpBtn = totpForm.querySelector('button[type="submit"]');
+    let challengeToken = '';
+
+    function showTotpStep(token) {
+        challengeToken = token;
+        loginForm.style.display = 'none';
+        totpForm.style.display = 'block';
+        totpInput.focus();
+    }
+
+    function resetToPinStep(message) {
+        challengeToken = '';
+        totpForm.style.display = 'none';
+        totpInput.value = '';
+        loginForm.style.display = 'block';
+        pinInput.value = '';
+        loginBtn.disabled = false;
+        loginBtn.textContent = 'Login';
+        showErrorMessage(message);
+    }
+
+    totpForm.addEventListener('submit', async function(e) {
+        e.preventDefault();
+
+        hideErrorMessage();
+
+        const code = totpInput.value.trim();
+        if (code === '') {
+            totpError.textContent = 'Authentication code is required';
+            totpInput.classList.add('error');
+            return;
+        }
+        totpError.textContent = '';
+        totpInput.classList.remove('error');
+
+        totpBtn.disabled = true;
+        totpBtn.textContent = 'Verifying...';
+
+        try {
+            const response = await fetch('/api/auth/login/totp', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json'
+                },
+                body: JSON.stringify({ challenge_token: challengeToken, code: code })
+            });
+
+            const data = await response.json();
+
+            if (response.ok) {
+                window.location.href = data.must_change_pin ? '/change-pin' : '/';
+                return;
+            }
+
+            if (data.restart_login) {
+                // Too many wrong codes or too slow, start over with the PIN
+                resetToPinStep('Please log in again');
+                return;
+            }
+
+            showErrorMessage(data.error || 'Failed to authenticate');
+        } catch (error) {
+            console.error('Login error:', error);
+            showErrorMessage('An error occurred. Please try again.');
+        }
+
+        totpBtn.disabled = false;
+        totpBtn.textContent = 'Verify';
+    });
 });
 
 // Hamburger menu toggle functionality
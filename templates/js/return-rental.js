@@ -52,9 +52,8 @@ function setCurrentDateTime() {
 // Load customers from API
 async function loadCustomers() {
     try {
-        const response = await fetch('/api/customers');
-        if (response.ok) {
-            customers = await response.json();
+        customers = await fetchAllPages('/api/customers');
+        if (customers) {
             customers.forEach(customer => {
                 const option = document.createElement('option');
                 option.value = customer.id;
@@ -85,9 +84,8 @@ customerSelect.addEventListener('change', async function() {
     }
 
     try {
-        const response = await fetch('/api/rentals');
-        if (response.ok) {
-            const allRentals = await response.json();
+        const allRentals = await fetchAllPages(`/api/rentals?customer_id=${this.value}&status=1`);
+        if (allRentals) {
             // Filter for active rentals (status 1 = rent) for selected customer
             rentals = allRentals.filter(rental =>
                 rental.id_clothing_customer === parseInt(this.value) &&
@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// Picture variants, every uploaded picture is stored in each of these sizes
+const (
+	PICTURE_VARIANT_FULL    = "full"
+	PICTURE_VARIANT_PREVIEW = "preview"
+	PICTURE_VARIANT_THUMB   = "thumb"
+)
+
+const (
+	// PICTURE_FULL_SIZE is the longest side of the full picture, phone photos are scaled down to it
+	PICTURE_FULL_SIZE = 2048
+
+	// PICTURE_PREVIEW_SIZE is the longest side of the preview shown on detail pages
+	PICTURE_PREVIEW_SIZE = 800
+
+	// PICTURE_THUMB_SIZE is the side of the square thumbnail shown in lists
+	PICTURE_THUMB_SIZE = 200
+
+	// PICTURE_JPEG_QUALITY is used for every stored variant
+	PICTURE_JPEG_QUALITY = 85
+
+	// PICTURE_MAX_PIXELS refuses images whose decoded size would exhaust memory
+	PICTURE_MAX_PIXELS = 50_000_000
+
+	PICTURE_CONTENT_TYPE = "image/jpeg"
+	PICTURE_EXTENSION    = ".jpg"
+)
+
+var ErrUnsupportedImage = errors.New("picture must be a JPEG, PNG or WebP image")
+
+// ProcessedPicture is one encoded variant of an uploaded picture
+type ProcessedPicture struct {
+	Data   []byte
+	Width  int
+	Height int
+}
+
+// PictureVariants lists the stored variants, the full picture first
+func PictureVariants() []string {
+	return []string{PICTURE_VARIANT_FULL, PICTURE_VARIANT_PREVIEW, PICTURE_VARIANT_THUMB}
+}
+
+// ProcessPicture validates an uploaded picture by its content, applies the EXIF orientation
+// and re-encodes it as JPEG in every variant size. Re-encoding drops all metadata such as GPS positions.
+func ProcessPicture(data []byte) (map[string]ProcessedPicture, error) {
+	var decode func([]byte) (image.Image, error)
+	var decodeConfig func([]byte) (image.Config, error)
+
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		decode = func(b []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(b)) }
+		decodeConfig = func(b []byte) (image.Config, error) { return jpeg.DecodeConfig(bytes.NewReader(b)) }
+	case "image/png":
+		decode = func(b []byte) (image.Image, error) { return png.Decode(bytes.NewReader(b)) }
+		decodeConfig = func(b []byte) (image.Config, error) { return png.DecodeConfig(bytes.NewReader(b)) }
+	case "image/webp":
+		decode = func(b []byte) (image.Image, error) { return webp.Decode(bytes.NewReader(b)) }
+		decodeConfig = func(b []byte) (image.Config, error) { return webp.DecodeConfig(bytes.NewReader(b)) }
+	default:
+		return nil, ErrUnsupportedImage
+	}
+
+	config, err := decodeConfig(data)
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrUnsupportedImage
+	}
+	if config.Width*config.Height > PICTURE_MAX_PIXELS {
+		return nil, errors.New("picture dimensions are too large")
+	}
+
+	img, err := decode(data)
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	// Scale down before rotating, the bounding box is square so the order does not matter
+	full := orientImage(fitImage(img, PICTURE_FULL_SIZE), jpegOrientation(data))
+
+	variants := map[string]image.Image{
+		PICTURE_VARIANT_FULL:    full,
+		PICTURE_VARIANT_PREVIEW: fitImage(full, PICTURE_PREVIEW_SIZE),
+		PICTURE_VARIANT_THUMB:   thumbImage(full, PICTURE_THUMB_SIZE),
+	}
+
+	result := make(map[string]ProcessedPicture, len(variants))
+	for name, variant := range variants {
+		encoded, err := encodeJpeg(variant)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = ProcessedPicture{Data: encoded, Width: variant.Bounds().Dx(), Height: variant.Bounds().Dy()}
+	}
+	return result, nil
+}
+
+// fitImage scales an image down so its longest side is at most size, smaller images are kept as they are
+func fitImage(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// thumbImage crops the centre square of an image and scales it to size x size
+func thumbImage(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	crop := image.Rect(x, y, x+side, y+side)
+
+	dst := image.NewRGBA(image.Rect(0, 0, min(size, side), min(size, side)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+	return dst
+}
+
+// encodeJpeg flattens transparency onto white and encodes the image as JPEG
+func encodeJpeg(img image.Image) ([]byte, error) {
+	bounds := img.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: PICTURE_JPEG_QUALITY}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// orientImage rotates and mirrors an image according to an EXIF orientation value (1 to 8)
+func orientImage(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// Orientations 5 to 8 swap width and height
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = width-1-x, y
+			case 3: // rotated 180
+				dx, dy = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = height-1-y, x
+			case 7: // mirrored along the top-right diagonal
+				dx, dy = height-1-y, width-1-x
+			case 8: // rotated 90 counter clockwise
+				dx, dy = y, width-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag of a JPEG, 1 (upright) when there is none
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+
+		// APP1 holds the EXIF data, the image data starts at SOS and carries no more metadata
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		if marker == 0xDA {
+			return 1
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation looks up tag 0x0112 in the first IFD of a TIFF structure
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			value := int(order.Uint16(tiff[entry+8:]))
+			if value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// Kinds of size measurements. Height is the body height a size fits, the others are garment measurements.
+const (
+	MEASUREMENT_CHEST  = "chest"
+	MEASUREMENT_WAIST  = "waist"
+	MEASUREMENT_HIP    = "hip"
+	MEASUREMENT_LENGTH = "length"
+	MEASUREMENT_SLEEVE = "sleeve"
+	MEASUREMENT_HEIGHT = "height"
+)
+
+// Measurement units, measurements are stored in millimetres and converted on the way in and out
+const (
+	MEASUREMENT_UNIT_MM = "mm"
+	MEASUREMENT_UNIT_CM = "cm"
+	MEASUREMENT_UNIT_IN = "in"
+
+	MEASUREMENT_DEFAULT_UNIT = MEASUREMENT_UNIT_CM
+)
+
+var ErrUnknownMeasurementUnit = errors.New("measurement unit must be mm, cm or in")
+
+// MeasurementTypes lists the kinds of measurements in the order they are shown
+func MeasurementTypes() []string {
+	return []string{
+		MEASUREMENT_CHEST, MEASUREMENT_WAIST, MEASUREMENT_HIP,
+		MEASUREMENT_LENGTH, MEASUREMENT_SLEEVE, MEASUREMENT_HEIGHT,
+	}
+}
+
+func IsMeasurementType(measurementType string) bool {
+	for _, t := range MeasurementTypes() {
+		if t == measurementType {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeMeasurementUnit lowercases a unit and falls back to the default unit when it is empty
+func NormalizeMeasurementUnit(unit string) (string, error) {
+	unit = strings.ToLower(strings.TrimSpace(unit))
+	switch unit {
+	case "":
+		return MEASUREMENT_DEFAULT_UNIT, nil
+	case MEASUREMENT_UNIT_MM, MEASUREMENT_UNIT_CM, MEASUREMENT_UNIT_IN:
+		return unit, nil
+	}
+	return "", ErrUnknownMeasurementUnit
+}
+
+// MillimetresPer returns how many millimetres one unit is
+func MillimetresPer(unit string) float64 {
+	switch unit {
+	case MEASUREMENT_UNIT_CM:
+		return 10
+	case MEASUREMENT_UNIT_IN:
+		return 25.4
+	}
+	return 1
+}
+
+// MeasurementToMillimetres converts a value in unit to whole millimetres, zero and negative values are refused
+func MeasurementToMillimetres(value float64, unit string) (int, error) {
+	unit, err := NormalizeMeasurementUnit(unit)
+	if err != nil {
+		return 0, err
+	}
+	if !(value > 0) || math.IsInf(value, 0) {
+		return 0, errors.New("measurement must be a positive number")
+	}
+	mm := int(math.Round(value * MillimetresPer(unit)))
+	if mm < 1 {
+		return 0, errors.New("measurement must be at least 1 mm")
+	}
+	return mm, nil
+}
+
+// MillimetresToMeasurement converts whole millimetres to unit, rounded to one decimal
+func MillimetresToMeasurement(mm int, unit string) float64 {
+	unit, err := NormalizeMeasurementUnit(unit)
+	if err != nil {
+		unit = MEASUREMENT_DEFAULT_UNIT
+	}
+	return math.Round(float64(mm)/MillimetresPer(unit)*10) / 10
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PIN_HASH_COST is the bcrypt work factor for staff PINs, deliberately slow since a PIN only has 6 digits
+const PIN_HASH_COST = 12
+
+// dummyPinHash is compared against when a username does not exist so both paths take the same time
+var dummyPinHash, _ = bcrypt.GenerateFromPassword([]byte("000000"), PIN_HASH_COST)
+
+// HashPin returns the salted bcrypt hash of a PIN
+func HashPin(pin string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), PIN_HASH_COST)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPin compares a PIN against its hash in constant time
+func CheckPin(hash, pin string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pin)) == nil
+}
+
+// CheckPinDummy burns the same time as CheckPin for unknown users and always fails
+func CheckPinDummy(pin string) bool {
+	_ = bcrypt.CompareHashAndPassword(dummyPinHash, []byte(pin))
+	return false
+}
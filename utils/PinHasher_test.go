@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashPin(t *testing.T) {
+	hash, err := HashPin("482913")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash == "482913" || !strings.HasPrefix(hash, "$2") {
+		t.Fatalf("HashPin = %q, want a bcrypt hash", hash)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+		pin  string
+		want bool
+	}{
+		{"same PIN", hash, "482913", true},
+		{"other PIN", hash, "482914", false},
+		{"empty PIN", hash, "", false},
+		{"PIN with extra digit", hash, "4829130", false},
+		{"empty hash", "", "482913", false},
+		{"plaintext stored as hash", "482913", "482913", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckPin(tt.hash, tt.pin); got != tt.want {
+				t.Errorf("CheckPin = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashPinIsSalted(t *testing.T) {
+	first, err := HashPin("482913")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := HashPin("482913")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("HashPin returned the same hash twice")
+	}
+	if !CheckPin(second, "482913") {
+		t.Error("second hash does not match its PIN")
+	}
+}
+
+func TestCheckPinDummy(t *testing.T) {
+	for _, pin := range []string{"000000", "482913", ""} {
+		if CheckPinDummy(pin) {
+			t.Errorf("CheckPinDummy(%q) = true", pin)
+		}
+	}
+}
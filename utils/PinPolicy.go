@@ -0,0 +1,25 @@
+package utils
+
+// IsWeakPin reports whether a PIN is trivially guessable: all the same digit,
+// or a run of consecutive digits going up or down such as 123456 or 987654.
+func IsWeakPin(pin string) bool {
+	if len(pin) < 2 {
+		return true
+	}
+
+	same, up, down := true, true, true
+	for i := 1; i < len(pin); i++ {
+		diff := int(pin[i]) - int(pin[i-1])
+		if diff != 0 {
+			same = false
+		}
+		if diff != 1 {
+			up = false
+		}
+		if diff != -1 {
+			down = false
+		}
+	}
+
+	return same || up || down
+}
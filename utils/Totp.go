@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// TOTP_PERIOD is the RFC 6238 time step in seconds
+	TOTP_PERIOD = 30
+
+	// TOTP_DIGITS is the length of a generated code
+	TOTP_DIGITS = 6
+
+	// TOTP_SKEW is how many time steps before and after now are still accepted to absorb clock drift
+	TOTP_SKEW = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTotpSecret returns a random 160 bit secret in base32, as expected by authenticator apps
+func GenerateTotpSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TotpStep returns the RFC 6238 time step of t
+func TotpStep(t time.Time) int64 {
+	return t.Unix() / TOTP_PERIOD
+}
+
+// TotpCodeAt returns the code of a secret for one time step (RFC 4226 HOTP with HMAC-SHA1)
+func TotpCodeAt(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimSpace(secret)))
+	if err != nil {
+		return "", err
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", TOTP_DIGITS, value%1000000), nil
+}
+
+// VerifyTotp checks a code around time t. Steps at or before lastStep are refused so a code cannot be replayed.
+// It returns the matched step, which the caller stores as the new lastStep.
+func VerifyTotp(secret, code string, t time.Time, lastStep int64) (int64, bool) {
+	if len(code) != TOTP_DIGITS {
+		return 0, false
+	}
+
+	now := TotpStep(t)
+	for step := now - TOTP_SKEW; step <= now+TOTP_SKEW; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := TotpCodeAt(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TotpProvisioningURI returns the otpauth:// URI that authenticator apps import, usually through a QR code
+func TotpProvisioningURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTP_DIGITS))
+	params.Set("period", fmt.Sprint(TOTP_PERIOD))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// GenerateRecoveryCode returns a one-time recovery code such as ABCD-EFGH-JKLM
+func GenerateRecoveryCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := totpEncoding.EncodeToString(b)[:12]
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12], nil
+}
+
+// NormalizeRecoveryCode upper cases a recovery code and strips the separators people type in
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	if len(code) != 12 {
+		return code
+	}
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12]
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 seed "12345678901234567890" of the RFC 6238 test vectors, in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTotpCodeAt(t *testing.T) {
+	// RFC 6238 appendix B, cut down to the last 6 digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := TotpCodeAt(rfc6238Secret, TotpStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("TotpCodeAt(%d): %v", tt.unix, err)
+		}
+		if code != tt.code {
+			t.Errorf("TotpCodeAt(%d) = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+
+	if _, err := TotpCodeAt("not base32!", 1); err == nil {
+		t.Error("TotpCodeAt accepted an invalid secret")
+	}
+}
+
+func TestVerifyTotp(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := TotpStep(now)
+	codeAt := func(s int64) string {
+		code, _ := TotpCodeAt(rfc6238Secret, s)
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfc6238Secret, codeAt(step), 0, step, true},
+		{"previous step within skew", rfc6238Secret, codeAt(step - 1), 0, step - 1, true},
+		{"next step within skew", rfc6238Secret, codeAt(step + 1), 0, step + 1, true},
+		{"outside skew", rfc6238Secret, codeAt(step - 2), 0, 0, false},
+		{"replayed step", rfc6238Secret, codeAt(step), step, 0, false},
+		{"later step after use", rfc6238Secret, codeAt(step + 1), step, step + 1, true},
+		{"lower case secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", codeAt(step), 0, step, true},
+		{"wrong code", rfc6238Secret, "000000", 0, 0, false},
+		{"too short", rfc6238Secret, codeAt(step)[:5], 0, 0, false},
+		{"invalid secret", "not base32!", codeAt(step), 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := VerifyTotp(tt.secret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("VerifyTotp = (%d, %v), want (%d, %v)", gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateTotpSecret(t *testing.T) {
+	secret, err := GenerateTotpSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("secret length = %d, want 32", len(secret))
+	}
+	if _, err := TotpCodeAt(secret, 1); err != nil {
+		t.Errorf("generated secret does not decode: %v", err)
+	}
+}
+
+func TestTotpProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(TotpProvisioningURI("Clothing Retail", "owner", rfc6238Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Clothing Retail:owner" {
+		t.Errorf("uri = %s", uri)
+	}
+
+	params := uri.Query()
+	for name, want := range map[string]string{
+		"secret":    rfc6238Secret,
+		"issuer":    "Clothing Retail",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	} {
+		if got := params.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	code, err := GenerateRecoveryCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}$`).MatchString(code) {
+		t.Errorf("GenerateRecoveryCode = %q", code)
+	}
+
+	tests := []struct {
+		in, want string
+	}{
+		{"ABCD-EFGH-JKLM", "ABCD-EFGH-JKLM"},
+		{"abcd-efgh-jklm", "ABCD-EFGH-JKLM"},
+		{"abcdefghjklm", "ABCD-EFGH-JKLM"},
+		{" abcd efgh jklm ", "ABCD-EFGH-JKLM"},
+		{"abc-def", "ABCDEF"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeRecoveryCode(tt.in); got != tt.want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
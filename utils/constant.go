@@ -29,6 +29,7 @@ const (
 	CLOTHES_MOV_ACTION_NOT_RETURN int = 5
 	CLOTHES_MOV_ACTION_WRITE_OFF  int = 6
 	CLOTHES_MOV_ACTION_LOST       int = 7
+	CLOTHES_MOV_ACTION_TRANSFER   int = 8
 
 	CLOTHES_MOV_ACTION_BUY_STR        string = "BUY"
 	CLOTHES_MOV_ACTION_SELL_STR       string = "SELL"
@@ -37,6 +38,12 @@ const (
 	CLOTHES_MOV_ACTION_NOT_RETURN_STR string = "NOT RETURN"
 	CLOTHES_MOV_ACTION_WRITE_OFF_STR  string = "WRITE OFF"
 	CLOTHES_MOV_ACTION_LOST_STR       string = "LOST"
+	CLOTHES_MOV_ACTION_TRANSFER_STR   string = "TRANSFER"
+
+	CLOTHES_MOV_STATUS_ACTIVE       int    = 1
+	CLOTHES_MOV_STATUS_INACTIVE     int    = 2
+	CLOTHES_MOV_STATUS_ACTIVE_STR   string = "ACTIVE"
+	CLOTHES_MOV_STATUS_INACTIVE_STR string = "INACTIVE"
 
 	CLOTHES_RENT_STATUS_RENTED     int = 1
 	CLOTHES_RENT_STATUS_RETURN     int = 2
@@ -183,6 +190,8 @@ func ClothesMovActionTrans(status int) string {
 		return CLOTHES_MOV_ACTION_WRITE_OFF_STR
 	case CLOTHES_MOV_ACTION_LOST:
 		return CLOTHES_MOV_ACTION_LOST_STR
+	case CLOTHES_MOV_ACTION_TRANSFER:
+		return CLOTHES_MOV_ACTION_TRANSFER_STR
 	}
 	return ""
 }
@@ -203,6 +212,8 @@ func ClothesMovActionTransReverse(status string) int {
 		return CLOTHES_MOV_ACTION_WRITE_OFF
 	case CLOTHES_MOV_ACTION_LOST_STR:
 		return CLOTHES_MOV_ACTION_LOST
+	case CLOTHES_MOV_ACTION_TRANSFER_STR:
+		return CLOTHES_MOV_ACTION_TRANSFER
 	}
 	return 0
 }
@@ -216,6 +227,34 @@ func ClothesMovActionMap() map[int]string {
 		CLOTHES_MOV_ACTION_NOT_RETURN: CLOTHES_MOV_ACTION_NOT_RETURN_STR,
 		CLOTHES_MOV_ACTION_WRITE_OFF:  CLOTHES_MOV_ACTION_WRITE_OFF_STR,
 		CLOTHES_MOV_ACTION_LOST:       CLOTHES_MOV_ACTION_LOST_STR,
+		CLOTHES_MOV_ACTION_TRANSFER:   CLOTHES_MOV_ACTION_TRANSFER_STR,
+	}
+}
+
+func ClothesMovStatusTrans(status int) string {
+	switch status {
+	case CLOTHES_MOV_STATUS_ACTIVE:
+		return CLOTHES_MOV_STATUS_ACTIVE_STR
+	case CLOTHES_MOV_STATUS_INACTIVE:
+		return CLOTHES_MOV_STATUS_INACTIVE_STR
+	}
+	return ""
+}
+
+func ClothesMovStatusTransReverse(status string) int {
+	switch status {
+	case CLOTHES_MOV_STATUS_ACTIVE_STR:
+		return CLOTHES_MOV_STATUS_ACTIVE
+	case CLOTHES_MOV_STATUS_INACTIVE_STR:
+		return CLOTHES_MOV_STATUS_INACTIVE
+	}
+	return 0
+}
+
+func ClothesMovStatusMap() map[int]string {
+	return map[int]string{
+		CLOTHES_MOV_STATUS_ACTIVE:   CLOTHES_MOV_STATUS_ACTIVE_STR,
+		CLOTHES_MOV_STATUS_INACTIVE: CLOTHES_MOV_STATUS_INACTIVE_STR,
 	}
 }
 
@@ -292,3 +331,362 @@ func ClothesUserStatusMap() map[int]string {
 		CLOTHES_USER_STATUS_SUSPENDED: CLOTHES_USER_STATUS_SUSPENDED_STR,
 	}
 }
+
+const (
+	SESSION_STATUS_ACTIVE  int = 1
+	SESSION_STATUS_REVOKED int = 2
+	SESSION_STATUS_EXPIRED int = 3
+
+	SESSION_STATUS_ACTIVE_STR  string = "ACTIVE"
+	SESSION_STATUS_REVOKED_STR string = "REVOKED"
+	SESSION_STATUS_EXPIRED_STR string = "EXPIRED"
+)
+
+func SessionStatusTrans(status int) string {
+	switch status {
+	case SESSION_STATUS_ACTIVE:
+		return SESSION_STATUS_ACTIVE_STR
+	case SESSION_STATUS_REVOKED:
+		return SESSION_STATUS_REVOKED_STR
+	case SESSION_STATUS_EXPIRED:
+		return SESSION_STATUS_EXPIRED_STR
+	}
+	return ""
+}
+
+func SessionStatusTransReverse(status string) int {
+	switch status {
+	case SESSION_STATUS_ACTIVE_STR:
+		return SESSION_STATUS_ACTIVE
+	case SESSION_STATUS_REVOKED_STR:
+		return SESSION_STATUS_REVOKED
+	case SESSION_STATUS_EXPIRED_STR:
+		return SESSION_STATUS_EXPIRED
+	}
+	return 0
+}
+
+func SessionStatusMap() map[int]string {
+	return map[int]string{
+		SESSION_STATUS_ACTIVE:  SESSION_STATUS_ACTIVE_STR,
+		SESSION_STATUS_REVOKED: SESSION_STATUS_REVOKED_STR,
+		SESSION_STATUS_EXPIRED: SESSION_STATUS_EXPIRED_STR,
+	}
+}
+
+const (
+	USER_ROLE_OWNER   int = 1
+	USER_ROLE_MANAGER int = 2
+	USER_ROLE_CASHIER int = 3
+	USER_ROLE_VIEWER  int = 4
+
+	USER_ROLE_OWNER_STR   string = "OWNER"
+	USER_ROLE_MANAGER_STR string = "MANAGER"
+	USER_ROLE_CASHIER_STR string = "CASHIER"
+	USER_ROLE_VIEWER_STR  string = "VIEWER"
+)
+
+func UserRoleTrans(role int) string {
+	switch role {
+	case USER_ROLE_OWNER:
+		return USER_ROLE_OWNER_STR
+	case USER_ROLE_MANAGER:
+		return USER_ROLE_MANAGER_STR
+	case USER_ROLE_CASHIER:
+		return USER_ROLE_CASHIER_STR
+	case USER_ROLE_VIEWER:
+		return USER_ROLE_VIEWER_STR
+	}
+	return ""
+}
+
+func UserRoleTransReverse(role string) int {
+	switch role {
+	case USER_ROLE_OWNER_STR:
+		return USER_ROLE_OWNER
+	case USER_ROLE_MANAGER_STR:
+		return USER_ROLE_MANAGER
+	case USER_ROLE_CASHIER_STR:
+		return USER_ROLE_CASHIER
+	case USER_ROLE_VIEWER_STR:
+		return USER_ROLE_VIEWER
+	}
+	return 0
+}
+
+func UserRoleMap() map[int]string {
+	return map[int]string{
+		USER_ROLE_OWNER:   USER_ROLE_OWNER_STR,
+		USER_ROLE_MANAGER: USER_ROLE_MANAGER_STR,
+		USER_ROLE_CASHIER: USER_ROLE_CASHIER_STR,
+		USER_ROLE_VIEWER:  USER_ROLE_VIEWER_STR,
+	}
+}
+
+const (
+	LOGIN_ATTEMPT_KEY_USERNAME int = 1
+	LOGIN_ATTEMPT_KEY_IP       int = 2
+
+	LOGIN_ATTEMPT_KEY_USERNAME_STR string = "USERNAME"
+	LOGIN_ATTEMPT_KEY_IP_STR       string = "IP"
+)
+
+func LoginAttemptKeyTrans(keyType int) string {
+	switch keyType {
+	case LOGIN_ATTEMPT_KEY_USERNAME:
+		return LOGIN_ATTEMPT_KEY_USERNAME_STR
+	case LOGIN_ATTEMPT_KEY_IP:
+		return LOGIN_ATTEMPT_KEY_IP_STR
+	}
+	return ""
+}
+
+func LoginAttemptKeyTransReverse(keyType string) int {
+	switch keyType {
+	case LOGIN_ATTEMPT_KEY_USERNAME_STR:
+		return LOGIN_ATTEMPT_KEY_USERNAME
+	case LOGIN_ATTEMPT_KEY_IP_STR:
+		return LOGIN_ATTEMPT_KEY_IP
+	}
+	return 0
+}
+
+func LoginAttemptKeyMap() map[int]string {
+	return map[int]string{
+		LOGIN_ATTEMPT_KEY_USERNAME: LOGIN_ATTEMPT_KEY_USERNAME_STR,
+		LOGIN_ATTEMPT_KEY_IP:       LOGIN_ATTEMPT_KEY_IP_STR,
+	}
+}
+
+const (
+	API_TOKEN_STATUS_ACTIVE  int = 1
+	API_TOKEN_STATUS_REVOKED int = 2
+
+	API_TOKEN_STATUS_ACTIVE_STR  string = "ACTIVE"
+	API_TOKEN_STATUS_REVOKED_STR string = "REVOKED"
+)
+
+func ApiTokenStatusTrans(status int) string {
+	switch status {
+	case API_TOKEN_STATUS_ACTIVE:
+		return API_TOKEN_STATUS_ACTIVE_STR
+	case API_TOKEN_STATUS_REVOKED:
+		return API_TOKEN_STATUS_REVOKED_STR
+	}
+	return ""
+}
+
+func ApiTokenStatusTransReverse(status string) int {
+	switch status {
+	case API_TOKEN_STATUS_ACTIVE_STR:
+		return API_TOKEN_STATUS_ACTIVE
+	case API_TOKEN_STATUS_REVOKED_STR:
+		return API_TOKEN_STATUS_REVOKED
+	}
+	return 0
+}
+
+func ApiTokenStatusMap() map[int]string {
+	return map[int]string{
+		API_TOKEN_STATUS_ACTIVE:  API_TOKEN_STATUS_ACTIVE_STR,
+		API_TOKEN_STATUS_REVOKED: API_TOKEN_STATUS_REVOKED_STR,
+	}
+}
+
+const (
+	AUDIT_ACTION_CREATE int = 1
+	AUDIT_ACTION_UPDATE int = 2
+	AUDIT_ACTION_DELETE int = 3
+	AUDIT_ACTION_RETURN int = 4
+
+	AUDIT_ACTION_CREATE_STR string = "CREATE"
+	AUDIT_ACTION_UPDATE_STR string = "UPDATE"
+	AUDIT_ACTION_DELETE_STR string = "DELETE"
+	AUDIT_ACTION_RETURN_STR string = "RETURN"
+
+	AUDIT_ENTITY_CATEGORY     string = "clothing_category"
+	AUDIT_ENTITY_CATEGORY_SUB string = "clothing_category_sub"
+	AUDIT_ENTITY_CUSTOMER     string = "clothing_customer"
+	AUDIT_ENTITY_RENTAL       string = "clothing_rental"
+	AUDIT_ENTITY_SIZE         string = "clothing_size"
+	AUDIT_ENTITY_SIZE_CHART   string = "clothing_size_chart"
+	AUDIT_ENTITY_ATTRIBUTE    string = "clothing_attribute"
+	AUDIT_ENTITY_LOCATION     string = "clothing_location"
+)
+
+func AuditActionTrans(action int) string {
+	switch action {
+	case AUDIT_ACTION_CREATE:
+		return AUDIT_ACTION_CREATE_STR
+	case AUDIT_ACTION_UPDATE:
+		return AUDIT_ACTION_UPDATE_STR
+	case AUDIT_ACTION_DELETE:
+		return AUDIT_ACTION_DELETE_STR
+	case AUDIT_ACTION_RETURN:
+		return AUDIT_ACTION_RETURN_STR
+	}
+	return ""
+}
+
+func AuditActionTransReverse(action string) int {
+	switch action {
+	case AUDIT_ACTION_CREATE_STR:
+		return AUDIT_ACTION_CREATE
+	case AUDIT_ACTION_UPDATE_STR:
+		return AUDIT_ACTION_UPDATE
+	case AUDIT_ACTION_DELETE_STR:
+		return AUDIT_ACTION_DELETE
+	case AUDIT_ACTION_RETURN_STR:
+		return AUDIT_ACTION_RETURN
+	}
+	return 0
+}
+
+func AuditActionMap() map[int]string {
+	return map[int]string{
+		AUDIT_ACTION_CREATE: AUDIT_ACTION_CREATE_STR,
+		AUDIT_ACTION_UPDATE: AUDIT_ACTION_UPDATE_STR,
+		AUDIT_ACTION_DELETE: AUDIT_ACTION_DELETE_STR,
+		AUDIT_ACTION_RETURN: AUDIT_ACTION_RETURN_STR,
+	}
+}
+
+const (
+	SIZE_CHART_STATUS_ACTIVE   int = 1
+	SIZE_CHART_STATUS_INACTIVE int = 2
+
+	SIZE_CHART_STATUS_ACTIVE_STR   string = "ACTIVE"
+	SIZE_CHART_STATUS_INACTIVE_STR string = "INACTIVE"
+)
+
+func SizeChartStatusTrans(status int) string {
+	switch status {
+	case SIZE_CHART_STATUS_ACTIVE:
+		return SIZE_CHART_STATUS_ACTIVE_STR
+	case SIZE_CHART_STATUS_INACTIVE:
+		return SIZE_CHART_STATUS_INACTIVE_STR
+	}
+	return ""
+}
+
+func SizeChartStatusTransReverse(status string) int {
+	switch status {
+	case SIZE_CHART_STATUS_ACTIVE_STR:
+		return SIZE_CHART_STATUS_ACTIVE
+	case SIZE_CHART_STATUS_INACTIVE_STR:
+		return SIZE_CHART_STATUS_INACTIVE
+	}
+	return 0
+}
+
+func SizeChartStatusMap() map[int]string {
+	return map[int]string{
+		SIZE_CHART_STATUS_ACTIVE:   SIZE_CHART_STATUS_ACTIVE_STR,
+		SIZE_CHART_STATUS_INACTIVE: SIZE_CHART_STATUS_INACTIVE_STR,
+	}
+}
+
+const (
+	ATTRIBUTE_STATUS_ACTIVE   int = 1
+	ATTRIBUTE_STATUS_INACTIVE int = 2
+
+	ATTRIBUTE_STATUS_ACTIVE_STR   string = "ACTIVE"
+	ATTRIBUTE_STATUS_INACTIVE_STR string = "INACTIVE"
+)
+
+func AttributeStatusTrans(status int) string {
+	switch status {
+	case ATTRIBUTE_STATUS_ACTIVE:
+		return ATTRIBUTE_STATUS_ACTIVE_STR
+	case ATTRIBUTE_STATUS_INACTIVE:
+		return ATTRIBUTE_STATUS_INACTIVE_STR
+	}
+	return ""
+}
+
+func AttributeStatusTransReverse(status string) int {
+	switch status {
+	case ATTRIBUTE_STATUS_ACTIVE_STR:
+		return ATTRIBUTE_STATUS_ACTIVE
+	case ATTRIBUTE_STATUS_INACTIVE_STR:
+		return ATTRIBUTE_STATUS_INACTIVE
+	}
+	return 0
+}
+
+func AttributeStatusMap() map[int]string {
+	return map[int]string{
+		ATTRIBUTE_STATUS_ACTIVE:   ATTRIBUTE_STATUS_ACTIVE_STR,
+		ATTRIBUTE_STATUS_INACTIVE: ATTRIBUTE_STATUS_INACTIVE_STR,
+	}
+}
+
+const (
+	LOCATION_TYPE_STORE     int = 1
+	LOCATION_TYPE_WAREHOUSE int = 2
+
+	LOCATION_TYPE_STORE_STR     string = "STORE"
+	LOCATION_TYPE_WAREHOUSE_STR string = "WAREHOUSE"
+)
+
+func LocationTypeTrans(locationType int) string {
+	switch locationType {
+	case LOCATION_TYPE_STORE:
+		return LOCATION_TYPE_STORE_STR
+	case LOCATION_TYPE_WAREHOUSE:
+		return LOCATION_TYPE_WAREHOUSE_STR
+	}
+	return ""
+}
+
+func LocationTypeTransReverse(locationType string) int {
+	switch locationType {
+	case LOCATION_TYPE_STORE_STR:
+		return LOCATION_TYPE_STORE
+	case LOCATION_TYPE_WAREHOUSE_STR:
+		return LOCATION_TYPE_WAREHOUSE
+	}
+	return 0
+}
+
+func LocationTypeMap() map[int]string {
+	return map[int]string{
+		LOCATION_TYPE_STORE:     LOCATION_TYPE_STORE_STR,
+		LOCATION_TYPE_WAREHOUSE: LOCATION_TYPE_WAREHOUSE_STR,
+	}
+}
+
+const (
+	LOCATION_STATUS_ACTIVE   int = 1
+	LOCATION_STATUS_INACTIVE int = 2
+
+	LOCATION_STATUS_ACTIVE_STR   string = "ACTIVE"
+	LOCATION_STATUS_INACTIVE_STR string = "INACTIVE"
+)
+
+func LocationStatusTrans(status int) string {
+	switch status {
+	case LOCATION_STATUS_ACTIVE:
+		return LOCATION_STATUS_ACTIVE_STR
+	case LOCATION_STATUS_INACTIVE:
+		return LOCATION_STATUS_INACTIVE_STR
+	}
+	return ""
+}
+
+func LocationStatusTransReverse(status string) int {
+	switch status {
+	case LOCATION_STATUS_ACTIVE_STR:
+		return LOCATION_STATUS_ACTIVE
+	case LOCATION_STATUS_INACTIVE_STR:
+		return LOCATION_STATUS_INACTIVE
+	}
+	return 0
+}
+
+func LocationStatusMap() map[int]string {
+	return map[int]string{
+		LOCATION_STATUS_ACTIVE:   LOCATION_STATUS_ACTIVE_STR,
+		LOCATION_STATUS_INACTIVE: LOCATION_STATUS_INACTIVE_STR,
+	}
+}
@@ -0,0 +1,62 @@
+package utils
+
+const (
+	PERM_CATALOG_VIEW   string = "catalog.view"
+	PERM_CATALOG_EDIT   string = "catalog.edit"
+	PERM_CATALOG_DELETE string = "catalog.delete"
+
+	PERM_CUSTOMER_VIEW string = "customer.view"
+	PERM_CUSTOMER_EDIT string = "customer.edit"
+
+	PERM_RENTAL_VIEW string = "rental.view"
+	PERM_RENTAL_EDIT string = "rental.edit"
+
+	PERM_REPORT_VIEW string = "report.view"
+	PERM_AUDIT_VIEW  string = "audit.view"
+
+	PERM_USER_MANAGE string = "user.manage"
+)
+
+// AllPermissions returns every permission known to the system
+func AllPermissions() []string {
+	return []string{
+		PERM_CATALOG_VIEW, PERM_CATALOG_EDIT, PERM_CATALOG_DELETE,
+		PERM_CUSTOMER_VIEW, PERM_CUSTOMER_EDIT,
+		PERM_RENTAL_VIEW, PERM_RENTAL_EDIT,
+		PERM_REPORT_VIEW, PERM_AUDIT_VIEW,
+		PERM_USER_MANAGE,
+	}
+}
+
+// RolePermissions returns the permissions granted to every role
+func RolePermissions() map[int][]string {
+	return map[int][]string{
+		USER_ROLE_OWNER: AllPermissions(),
+		USER_ROLE_MANAGER: {
+			PERM_CATALOG_VIEW, PERM_CATALOG_EDIT, PERM_CATALOG_DELETE,
+			PERM_CUSTOMER_VIEW, PERM_CUSTOMER_EDIT,
+			PERM_RENTAL_VIEW, PERM_RENTAL_EDIT,
+			PERM_REPORT_VIEW, PERM_AUDIT_VIEW,
+		},
+		USER_ROLE_CASHIER: {
+			PERM_CATALOG_VIEW,
+			PERM_CUSTOMER_VIEW, PERM_CUSTOMER_EDIT,
+			PERM_RENTAL_VIEW, PERM_RENTAL_EDIT,
+		},
+		USER_ROLE_VIEWER: {
+			PERM_CATALOG_VIEW,
+			PERM_CUSTOMER_VIEW,
+			PERM_RENTAL_VIEW,
+		},
+	}
+}
+
+// HasPermission reports whether a role has been granted a permission
+func HasPermission(role int, permission string) bool {
+	for _, p := range RolePermissions()[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}